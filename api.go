@@ -2,15 +2,39 @@ package llb
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 )
 
 type (
+	errorPayload struct {
+		Message    string         `json:"errorMessage"`
+		Type       string         `json:"errorType"`
+		StackTrace []string       `json:"stackTrace"`
+		Cause      []errorPayload `json:"cause,omitempty"`
+	}
+	// streamBody delivers a streamed response to the client and sets the error trailers once the stream has failed
+	streamBody struct {
+		*io.PipeReader
+		trailer          http.Header
+		errType, errBody string
+	}
+	// responseReportedError is returned when a response could not be posted and the invocation error was reported in its place, the invocation is over so the runtime keeps serving
+	responseReportedError struct {
+		error
+	}
+	// RuntimeAPIError is returned when the Runtime API answers a call with an unexpected status code
+	RuntimeAPIError struct {
+		StatusCode int
+		Endpoint   string
+		Body       string
+	}
 	httpClient interface {
 		Do(*http.Request) (*http.Response, error)
 	}
@@ -20,6 +44,8 @@ type (
 		nextUrl             string
 		initErrorUrl        string
 		client              httpClient
+		logger              *slog.Logger
+		crashOnPanic        bool
 	}
 	api interface {
 		getRuntimeInvocationNext() (resp *http.Response, err error)
@@ -35,9 +61,18 @@ const (
 	headerContentType  = "Content-Type"
 	defaultContentType = "application/json"
 
+	headerResponseMode    = "Lambda-Runtime-Function-Response-Mode"
+	streamingResponseMode = "streaming"
+
 	headerErrorType          = "Lambda-Runtime-Function-Error-Type"
+	headerErrorBody          = "Lambda-Runtime-Function-Error-Body"
 	defaultInitErrorHeader   = "Runtime.InitError"
 	defaultInvokeErrorHeader = "Runtime.InvokeError"
+
+	maxCauseDepth           = 16
+	timeoutErrorHeader      = "Runtime.HandlerTimeout"
+	handlerPanicHeader      = "Runtime.HandlerPanic"
+	responseSizeErrorHeader = "Function.ResponseSizeTooLarge"
 )
 
 var (
@@ -45,17 +80,91 @@ var (
 )
 
 func newDefaultAPI(client httpClient) defaultAPI {
-	domain := os.Getenv(envRuntimeDomain)
+	return newAPI(os.Getenv(envRuntimeDomain), client, slog.Default())
+}
 
+func newAPI(domain string, client httpClient, logger *slog.Logger) defaultAPI {
 	return defaultAPI{
 		domain:              domain,
 		invocationUrlPrefix: "http://" + domain + "/2018-06-01/runtime/invocation/",
 		nextUrl:             "http://" + domain + "/2018-06-01/runtime/invocation/next",
 		initErrorUrl:        "http://" + domain + "/2018-06-01/runtime/init/error",
 		client:              client,
+		logger:              logger,
 	}
 }
 
+// newErrorPayload returns the error type header and JSON body reported to the Runtime API for err, defaultHeader is used unless err is an Error
+func newErrorPayload(err error, defaultHeader string) (string, []byte) {
+	header := defaultHeader
+	payload := errorPayload{
+		Message:    err.Error(),
+		Type:       defaultHeader,
+		StackTrace: []string{},
+		Cause:      newCausePayloads(err, 0),
+	}
+
+	if err, ok := err.(Error); ok {
+		header = err.Header()
+		payload.Type = err.Type()
+	}
+
+	var tracer StackTracer
+	if errors.As(err, &tracer) {
+		payload.StackTrace = tracer.StackTrace()
+	}
+
+	body, _ := json.Marshal(payload)
+
+	return header, body
+}
+
+// newCausePayloads unwraps err into the payloads of its causes, wrappers that do not change the message (e.g. NewError) are skipped over
+func newCausePayloads(err error, depth int) []errorPayload {
+	if depth >= maxCauseDepth {
+		return nil
+	}
+
+	var causes []error
+	switch err := err.(type) {
+	case interface{ Unwrap() []error }:
+		causes = err.Unwrap()
+	case interface{ Unwrap() error }:
+		if cause := err.Unwrap(); cause != nil {
+			causes = []error{cause}
+		}
+	}
+
+	var payloads []errorPayload
+	for _, cause := range causes {
+		if cause == nil {
+			continue
+		}
+
+		if cause.Error() == err.Error() {
+			payloads = append(payloads, newCausePayloads(cause, depth+1)...)
+			continue
+		}
+
+		payload := errorPayload{
+			Message:    cause.Error(),
+			Type:       fmt.Sprintf("%T", cause),
+			StackTrace: []string{},
+			Cause:      newCausePayloads(cause, depth+1),
+		}
+		if cause, ok := cause.(Error); ok {
+			payload.Type = cause.Type()
+		}
+		if cause, ok := cause.(StackTracer); ok {
+			payload.StackTrace = cause.StackTrace()
+		}
+
+		payloads = append(payloads, payload)
+	}
+
+	return payloads
+}
+
 func (api defaultAPI) getRuntimeInvocationNext() (*http.Response, error) {
 	request, _ := http.NewRequest(
 		http.MethodGet,
@@ -72,25 +181,9 @@ func (api defaultAPI) getRuntimeInvocationNext() (*http.Response, error) {
 }
 
 func (api defaultAPI) postRuntimeInitError(err error) (*http.Response, error) {
-	log.Println("defaultAPI.postRuntimeInitError", err)
-
-	header := defaultInitErrorHeader
-	payload := struct {
-		Message    string   `json:"errorMessage"`
-		Type       string   `json:"errorType"`
-		StackTrace []string `json:"stackTrace"`
-	}{
-		Message:    err.Error(),
-		Type:       defaultInitErrorHeader,
-		StackTrace: []string{},
-	}
-
-	if err, ok := err.(Error); ok {
-		header = err.Header()
-		payload.Type = err.Type()
-	}
+	api.logger.Error("reporting init error", "error", err)
 
-	body, _ := json.Marshal(payload)
+	header, body := newErrorPayload(err, defaultInitErrorHeader)
 
 	request, _ := http.NewRequest(
 		http.MethodPost,
@@ -102,42 +195,19 @@ func (api defaultAPI) postRuntimeInitError(err error) (*http.Response, error) {
 	resp, err := api.client.Do(request)
 	if err != nil {
 		return resp, fmt.Errorf("%w; error submitting defaultAPI.postRuntimeInitError request", err)
-	} else {
-		msg, _ := io.ReadAll(resp.Body)
-
-		switch resp.StatusCode {
-		case http.StatusAccepted:
-			return resp, fmt.Errorf("accepted status code (202) defaultAPI.postRuntimeInitError\n%s", string(msg))
-		case http.StatusForbidden:
-			return resp, fmt.Errorf("forbidden status code (403) defaultAPI.postRuntimeInitError\n%s", string(msg))
-		case http.StatusInternalServerError:
-			return resp, fmt.Errorf("container Error status code (500) defaultAPI.postRuntimeInitError\n%s", string(msg))
-		default:
-			return resp, fmt.Errorf("invalid status code (%d) defaultAPI.postRuntimeInitError\n%s", resp.StatusCode, string(msg))
-		}
 	}
-}
 
-func (api defaultAPI) postRuntimeInvocationError(requestId string, err error) (*http.Response, error) {
-	log.Println("defaultAPI.postRuntimeInvocationError", requestId, err)
-
-	header := defaultInvokeErrorHeader
-	payload := struct {
-		Message    string   `json:"errorMessage"`
-		Type       string   `json:"errorType"`
-		StackTrace []string `json:"stackTrace"`
-	}{
-		Message:    err.Error(),
-		Type:       defaultInvokeErrorHeader,
-		StackTrace: []string{},
+	if err := checkStatus(resp, api.initErrorUrl); err != nil {
+		return resp, fmt.Errorf("%w; defaultAPI.postRuntimeInitError", err)
 	}
 
-	if err, ok := err.(Error); ok {
-		header = err.Header()
-		payload.Type = err.Type()
-	}
+	return resp, nil
+}
 
-	body, _ := json.Marshal(payload)
+func (api defaultAPI) postRuntimeInvocationError(requestId string, err error) (*http.Response, error) {
+	api.logger.Error("reporting invocation error", logKeyRequestId, requestId, "error", err)
+
+	header, body := newErrorPayload(err, defaultInvokeErrorHeader)
 
 	request, _ := http.NewRequest(
 		http.MethodPost,
@@ -149,31 +219,35 @@ func (api defaultAPI) postRuntimeInvocationError(requestId string, err error) (*
 	resp, err := api.client.Do(request)
 	if err != nil {
 		return resp, fmt.Errorf("%w; error submitting defaultAPI.postRuntimeInvocationError request", err)
-	} else {
-		msg, _ := io.ReadAll(resp.Body)
-
-		switch resp.StatusCode {
-		case http.StatusAccepted:
-			return resp, fmt.Errorf("accepted status code (202) defaultAPI.postRuntimeInvocationError for request: %s\n%s", requestId, string(msg))
-		case http.StatusBadRequest:
-			return resp, fmt.Errorf("bad Request status code (400) defaultAPI.postRuntimeInvocationError for request: %s\n%s", requestId, string(msg))
-		case http.StatusForbidden:
-			return resp, fmt.Errorf("forbidden status code (403) defaultAPI.postRuntimeInvocationError for request: %s\n%s", requestId, string(msg))
-		case http.StatusInternalServerError:
-			return resp, fmt.Errorf("container Error status code (500) defaultAPI.postRuntimeInvocationError for request: %s\n%s", requestId, string(msg))
-		default:
-			return resp, fmt.Errorf("invalid status code (%d) defaultAPI.postRuntimeInvocationError for request: %s\n%s", resp.StatusCode, requestId, string(msg))
-		}
 	}
+
+	if err := checkStatus(resp, request.URL.String()); err != nil {
+		return resp, fmt.Errorf("%w; defaultAPI.postRuntimeInvocationError for request: %s", err, requestId)
+	}
+
+	return resp, nil
 }
 
 func (api defaultAPI) postRuntimeInvocationResponse(requestId string, response io.Reader) (*http.Response, error) {
+	if response, ok := response.(StreamingResponse); ok {
+		return api.postRuntimeInvocationStream(requestId, response)
+	}
+
 	req, _ := http.NewRequest(
 		http.MethodPost,
 		api.invocationUrlPrefix+requestId+"/response",
 		response,
 	)
 
+	// a response buffered by runtime.limitResponse can be sent again by a retry
+	if response, ok := response.(defaultReponse); ok {
+		if buf, ok := response.Reader.(*bytes.Buffer); ok {
+			body := buf.Bytes()
+			req.ContentLength = int64(len(body))
+			req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+		}
+	}
+
 	if response, ok := response.(Response); ok {
 		req.Header.Add(headerContentType, response.ContentType())
 	} else {
@@ -181,10 +255,124 @@ func (api defaultAPI) postRuntimeInvocationResponse(requestId string, response i
 	}
 
 	resp, err := api.client.Do(req)
+	if err == nil {
+		err = checkStatus(resp, req.URL.String())
+	}
+
 	if err != nil {
 		err = fmt.Errorf("%w; defaultAPI.postRuntimeInvocationResponse for request: %s", err, requestId)
-		return api.postRuntimeInvocationError(requestId, err)
+		return resp, api.reportResponseError(requestId, err)
+	}
+
+	return resp, nil
+}
+
+// reportResponseError reports err as the invocation error after the response could not be posted, err is wrapped in a responseReportedError if the report was accepted and joined with the error of the report otherwise
+func (api defaultAPI) reportResponseError(requestId string, err error) error {
+	if _, postErr := api.postRuntimeInvocationError(requestId, err); postErr != nil {
+		return errors.Join(err, postErr)
+	}
+
+	return responseReportedError{err}
+}
+
+// postRuntimeInvocationStream streams response to the response endpoint with chunked transfer encoding, if the stream fails after it started the error is reported in the request trailers
+func (api defaultAPI) postRuntimeInvocationStream(requestId string, response StreamingResponse) (*http.Response, error) {
+	pr, pw := io.Pipe()
+	body := &streamBody{
+		PipeReader: pr,
+		trailer:    http.Header{headerErrorType: nil, headerErrorBody: nil},
+	}
+
+	req, _ := http.NewRequest(
+		http.MethodPost,
+		api.invocationUrlPrefix+requestId+"/response",
+		body,
+	)
+	req.TransferEncoding = []string{"chunked"}
+	req.Trailer = body.trailer
+	req.Header.Add(headerContentType, response.ContentType())
+	req.Header.Add(headerResponseMode, streamingResponseMode)
+
+	streamErr := make(chan error, 1)
+	go func() {
+		err := api.stream(response, pw)
+		if err != nil {
+			header, payload := newErrorPayload(err, defaultInvokeErrorHeader)
+			body.errType = header
+			body.errBody = base64.StdEncoding.EncodeToString(payload)
+		}
+
+		pw.Close()
+		streamErr <- err
+	}()
+
+	resp, err := api.client.Do(req)
+	pr.CloseWithError(io.ErrClosedPipe)
+
+	if err != nil {
+		<-streamErr
+		return resp, fmt.Errorf("%w; defaultAPI.postRuntimeInvocationStream for request: %s", err, requestId)
+	}
+
+	if err := checkStatus(resp, req.URL.String()); err != nil {
+		<-streamErr
+		err = fmt.Errorf("%w; defaultAPI.postRuntimeInvocationStream for request: %s", err, requestId)
+		return resp, api.reportResponseError(requestId, err)
+	}
+
+	if err := <-streamErr; err != nil {
+		api.logger.Error("response stream failed", logKeyRequestId, requestId, "error", err)
+		return resp, fmt.Errorf("%w; defaultAPI.postRuntimeInvocationStream stream failed for request: %s", err, requestId)
 	}
 
 	return resp, nil
 }
+
+// stream writes response to w, unless crashOnPanic is set a panic is recovered and returned as a Runtime.HandlerPanic Error
+func (api defaultAPI) stream(response StreamingResponse, w io.Writer) (err error) {
+	if !api.crashOnPanic {
+		defer func() {
+			if v := recover(); v != nil {
+				err = newHandlerPanic(v)
+			}
+		}()
+	}
+
+	return response.Stream(w)
+}
+
+// checkStatus returns a RuntimeAPIError with the body of resp unless the Runtime API accepted the call
+func checkStatus(resp *http.Response, endpoint string) error {
+	if resp.StatusCode == http.StatusAccepted {
+		return nil
+	}
+
+	var body []byte
+	if resp.Body != nil {
+		body, _ = io.ReadAll(resp.Body)
+	}
+
+	return RuntimeAPIError{StatusCode: resp.StatusCode, Endpoint: endpoint, Body: string(body)}
+}
+
+func (err responseReportedError) Unwrap() error { return err.error }
+
+func (err RuntimeAPIError) Error() string {
+	if err.Body == "" {
+		return fmt.Sprintf("runtime API returned status code (%d) for %s", err.StatusCode, err.Endpoint)
+	}
+
+	return fmt.Sprintf("runtime API returned status code (%d) for %s\n%s", err.StatusCode, err.Endpoint, err.Body)
+}
+
+// Read sets the error trailers when the stream ends, the client writes trailers after reading the body to EOF so they are complete by then
+func (sb *streamBody) Read(p []byte) (int, error) {
+	n, err := sb.PipeReader.Read(p)
+	if err == io.EOF && sb.errType != "" {
+		sb.trailer.Set(headerErrorType, sb.errType)
+		sb.trailer.Set(headerErrorBody, sb.errBody)
+	}
+
+	return n, err
+}
@@ -2,11 +2,17 @@ package llb
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -56,6 +62,7 @@ func Test_newDefaultAPI(t *testing.T) {
 		nextUrl:             "http://domain/2018-06-01/runtime/invocation/next",
 		initErrorUrl:        "http://domain/2018-06-01/runtime/init/error",
 		client:              nil,
+		logger:              slog.Default(),
 	}
 
 	if !reflect.DeepEqual(got, want) {
@@ -146,7 +153,7 @@ func Test_defaultAPI_postRuntimeInitError(t *testing.T) {
 			args: args{
 				err: errors.New("error"),
 			},
-			wantErr: true,
+			wantErr: false,
 		},
 		{
 			name: "Error 403",
@@ -241,7 +248,7 @@ func Test_defaultAPI_postRuntimeInvocationError(t *testing.T) {
 			args: args{
 				err: errors.New("error"),
 			},
-			wantErr: true,
+			wantErr: false,
 		},
 		{
 			name: "Error 400",
@@ -318,7 +325,7 @@ func Test_defaultAPI_postRuntimeInvocationResponse(t *testing.T) {
 			name: "Success",
 			api: newDefaultAPI(mockHttpClient{
 				do: func(r *http.Request) (*http.Response, error) {
-					return nil, nil
+					return valid202Response(), nil
 				},
 			}),
 			args: args{
@@ -331,7 +338,7 @@ func Test_defaultAPI_postRuntimeInvocationResponse(t *testing.T) {
 			name: "Custom Success",
 			api: newDefaultAPI(mockHttpClient{
 				do: func(r *http.Request) (*http.Response, error) {
-					return nil, nil
+					return valid202Response(), nil
 				},
 			}),
 			args: args{
@@ -353,6 +360,22 @@ func Test_defaultAPI_postRuntimeInvocationResponse(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Payload Too Large",
+			api: newDefaultAPI(mockHttpClient{
+				do: func(r *http.Request) (*http.Response, error) {
+					if strings.HasSuffix(r.URL.Path, "/error") {
+						return valid202Response(), nil
+					}
+					return &http.Response{StatusCode: http.StatusRequestEntityTooLarge, Body: io.NopCloser(bytes.NewBufferString("too large"))}, nil
+				},
+			}),
+			args: args{
+				requestId: "request",
+				response:  nil,
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -364,3 +387,221 @@ func Test_defaultAPI_postRuntimeInvocationResponse(t *testing.T) {
 		})
 	}
 }
+
+func Test_defaultAPI_postRuntimeInvocationStream(t *testing.T) {
+	tests := []struct {
+		name            string
+		stream          func(w io.Writer) error
+		wantBody        string
+		wantErrorType   string
+		wantPayloadType string
+		wantErr         bool
+	}{
+		{
+			name: "Success",
+			stream: func(w io.Writer) error {
+				io.WriteString(w, "first,")
+				io.WriteString(w, "second")
+				return nil
+			},
+			wantBody: "first,second",
+		},
+		{
+			name: "Mid Stream Error",
+			stream: func(w io.Writer) error {
+				io.WriteString(w, "first,")
+				return NewError(errors.New("error"), "header", "typ")
+			},
+			wantBody:        "first,",
+			wantErrorType:   "header",
+			wantPayloadType: "typ",
+			wantErr:         true,
+		},
+		{
+			name: "Mid Stream Panic",
+			stream: func(w io.Writer) error {
+				io.WriteString(w, "first,")
+				panic("boom")
+			},
+			wantBody:        "first,",
+			wantErrorType:   handlerPanicHeader,
+			wantPayloadType: handlerPanicHeader,
+			wantErr:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody, gotMode, gotType string
+			var gotTrailer http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				gotBody = string(data)
+				gotMode = r.Header.Get(headerResponseMode)
+				gotType = r.Header.Get(headerContentType)
+				gotTrailer = r.Trailer
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer server.Close()
+
+			api := newAPI(strings.TrimPrefix(server.URL, "http://"), server.Client(), slog.Default())
+			_, err := api.postRuntimeInvocationResponse("request", NewStreamingResponse(tt.stream, "text/plain"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("defaultAPI.postRuntimeInvocationResponse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotBody != tt.wantBody {
+				t.Errorf("streamed body = %q, want %q", gotBody, tt.wantBody)
+			}
+			if gotMode != streamingResponseMode {
+				t.Errorf("response mode header = %q, want %q", gotMode, streamingResponseMode)
+			}
+			if gotType != "text/plain" {
+				t.Errorf("content type header = %q, want text/plain", gotType)
+			}
+			if got := gotTrailer.Get(headerErrorType); got != tt.wantErrorType {
+				t.Errorf("error type trailer = %q, want %q", got, tt.wantErrorType)
+			}
+			if tt.wantErrorType != "" {
+				payload, _ := base64.StdEncoding.DecodeString(gotTrailer.Get(headerErrorBody))
+				if !strings.Contains(string(payload), `"errorType":"`+tt.wantPayloadType+`"`) {
+					t.Errorf("error body trailer = %s", payload)
+				}
+			}
+		})
+	}
+}
+
+func Test_defaultAPI_postRuntimeInvocationStream_requestError(t *testing.T) {
+	api := newDefaultAPI(mockHttpClient{
+		do: func(r *http.Request) (*http.Response, error) {
+			return nil, errors.New("error")
+		},
+	})
+
+	_, err := api.postRuntimeInvocationResponse("request", NewStreamingResponse(func(w io.Writer) error {
+		_, err := io.WriteString(w, "data")
+		return err
+	}, "text/plain"))
+	if err == nil {
+		t.Fatal("defaultAPI.postRuntimeInvocationResponse() did not return the request error")
+	}
+}
+
+func Test_defaultAPI_postRuntimeInvocationResponse_fallback(t *testing.T) {
+	var errorBody string
+	api := newDefaultAPI(mockHttpClient{
+		do: func(r *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(r.URL.Path, "/error") {
+				data, _ := io.ReadAll(r.Body)
+				errorBody = string(data)
+				return valid202Response(), nil
+			}
+			return &http.Response{StatusCode: http.StatusRequestEntityTooLarge, Body: io.NopCloser(bytes.NewBufferString("too large"))}, nil
+		},
+	})
+
+	_, err := api.postRuntimeInvocationResponse("request", bytes.NewBufferString("{}"))
+
+	var apiErr RuntimeAPIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("defaultAPI.postRuntimeInvocationResponse() error = %v, want a RuntimeAPIError", err)
+	}
+	if apiErr.StatusCode != http.StatusRequestEntityTooLarge || apiErr.Body != "too large" || !strings.HasSuffix(apiErr.Endpoint, "/request/response") {
+		t.Errorf("RuntimeAPIError = %+v", apiErr)
+	}
+	if !strings.Contains(errorBody, "status code (413)") {
+		t.Errorf("reported invocation error = %s, want the response status", errorBody)
+	}
+	if !isResponseReported(err) {
+		t.Errorf("defaultAPI.postRuntimeInvocationResponse() error = %v, want it marked as reported", err)
+	}
+}
+
+func Test_defaultAPI_postRuntimeInvocationResponse_fallbackFailed(t *testing.T) {
+	api := newDefaultAPI(mockHttpClient{
+		do: func(r *http.Request) (*http.Response, error) {
+			return valid400Response(), nil
+		},
+	})
+
+	_, err := api.postRuntimeInvocationResponse("request", bytes.NewBufferString("{}"))
+	if err == nil || isResponseReported(err) {
+		t.Errorf("defaultAPI.postRuntimeInvocationResponse() error = %v, want an error that was not reported", err)
+	}
+}
+
+func Test_defaultAPI_postRuntimeInitError_status(t *testing.T) {
+	api := newDefaultAPI(mockHttpClient{
+		do: func(r *http.Request) (*http.Response, error) {
+			return valid403Response(), nil
+		},
+	})
+
+	_, err := api.postRuntimeInitError(errors.New("error"))
+
+	var apiErr RuntimeAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden || !strings.HasSuffix(apiErr.Endpoint, "/runtime/init/error") {
+		t.Errorf("defaultAPI.postRuntimeInitError() error = %v, want a 403 RuntimeAPIError", err)
+	}
+}
+
+func Test_newErrorPayload(t *testing.T) {
+	root := errors.New("root")
+	other := errors.New("other")
+
+	tests := []struct {
+		name       string
+		err        error
+		wantHeader string
+		want       errorPayload
+	}{
+		{
+			name:       "Plain",
+			err:        root,
+			wantHeader: defaultInvokeErrorHeader,
+			want:       errorPayload{Message: "root", Type: defaultInvokeErrorHeader, StackTrace: []string{}},
+		},
+		{
+			name:       "Wrapped",
+			err:        NewError(fmt.Errorf("outer: %w", root), "header", "typ"),
+			wantHeader: "header",
+			want: errorPayload{Message: "outer: root", Type: "typ", StackTrace: []string{}, Cause: []errorPayload{
+				{Message: "root", Type: "*errors.errorString", StackTrace: []string{}},
+			}},
+		},
+		{
+			name:       "Joined",
+			err:        errors.Join(root, NewError(other, "header", "typ")),
+			wantHeader: defaultInvokeErrorHeader,
+			want: errorPayload{Message: "root\nother", Type: defaultInvokeErrorHeader, StackTrace: []string{}, Cause: []errorPayload{
+				{Message: "root", Type: "*errors.errorString", StackTrace: []string{}},
+				{Message: "other", Type: "typ", StackTrace: []string{}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, body := newErrorPayload(tt.err, defaultInvokeErrorHeader)
+			if header != tt.wantHeader {
+				t.Errorf("newErrorPayload() header = %s, want %s", header, tt.wantHeader)
+			}
+
+			got := errorPayload{}
+			json.Unmarshal(body, &got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newErrorPayload() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_newErrorPayload_stack(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", NewErrorWithStack(errors.New("root"), "header", "typ"))
+
+	_, body := newErrorPayload(err, defaultInvokeErrorHeader)
+
+	got := errorPayload{}
+	json.Unmarshal(body, &got)
+	if len(got.StackTrace) == 0 || !strings.Contains(got.StackTrace[0], "Test_newErrorPayload_stack") {
+		t.Errorf("newErrorPayload() stackTrace = %v", got.StackTrace)
+	}
+}
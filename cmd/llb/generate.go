@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/RileyMcCuen/llb/pkg/eventfixtures"
+)
+
+type (
+	generateFlags struct {
+		body       string
+		count      int
+		method     string
+		path       string
+		source     string
+		detailType string
+		detail     string
+		compact    bool
+	}
+
+	eventGenerator func(flags generateFlags) (any, error)
+)
+
+var (
+	// eventGenerators maps the event types of generate-event to the eventfixtures builder of their handlerutil handler
+	eventGenerators = map[string]eventGenerator{
+		"sqs":              generateSQS,
+		"sns":              generateSNS,
+		"cloudwatch-event": generateCloudWatchEvent,
+		"apigateway":       generateAPIGateway,
+		"apigateway-v2":    generateAPIGatewayV2,
+	}
+)
+
+// generateEvent prints an event of the type named by the first argument as JSON
+func generateEvent(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := generateFlags{}
+	fs := flag.NewFlagSet("generate-event", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&flags.body, "body", "", "message or request body, each type has its own default")
+	fs.IntVar(&flags.count, "count", 1, "number of records for sqs and sns")
+	fs.StringVar(&flags.method, "method", "GET", "HTTP method for apigateway and apigateway-v2")
+	fs.StringVar(&flags.path, "path", "/", "request path for apigateway and apigateway-v2")
+	fs.StringVar(&flags.source, "source", "", "source for cloudwatch-event")
+	fs.StringVar(&flags.detailType, "detail-type", "", "detail-type for cloudwatch-event")
+	fs.StringVar(&flags.detail, "detail", "", "JSON detail for cloudwatch-event")
+	fs.BoolVar(&flags.compact, "compact", false, "print the event on one line")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: llb generate-event [flags] <type>")
+		fmt.Fprintln(stderr, "types:", strings.Join(eventTypes(), ", "))
+		fs.PrintDefaults()
+	}
+
+	// flags may come before or after the type
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional, args = append(positional, fs.Arg(0)), fs.Args()[1:]
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	generate, ok := eventGenerators[positional[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "llb generate-event: unknown event type %q, want one of %s\n", positional[0], strings.Join(eventTypes(), ", "))
+		return 2
+	}
+
+	event, err := generate(flags)
+	if err != nil {
+		fmt.Fprintln(stderr, "llb generate-event:", err)
+		return 1
+	}
+
+	var data []byte
+	if flags.compact {
+		data, err = json.Marshal(event)
+	} else {
+		data, err = json.MarshalIndent(event, "", "  ")
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "llb generate-event:", err)
+		return 1
+	}
+
+	fmt.Fprintln(stdout, string(data))
+	return 0
+}
+
+func eventTypes() []string {
+	types := make([]string, 0, len(eventGenerators))
+	for typ := range eventGenerators {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+
+	return types
+}
+
+func generateSQS(flags generateFlags) (any, error) {
+	b := eventfixtures.SQS()
+	for i := 0; i < flags.count; i++ {
+		b.WithMessage(orDefault(flags.body, eventfixtures.DefaultSQSBody))
+	}
+
+	return b.Build(), nil
+}
+
+func generateSNS(flags generateFlags) (any, error) {
+	b := eventfixtures.SNS()
+	for i := 0; i < flags.count; i++ {
+		b.WithMessage(orDefault(flags.body, eventfixtures.DefaultSNSMessage))
+	}
+
+	return b.Build(), nil
+}
+
+func generateCloudWatchEvent(flags generateFlags) (any, error) {
+	b := eventfixtures.CloudWatchEvent()
+	if flags.source != "" {
+		b.WithSource(flags.source)
+	}
+	if flags.detailType != "" {
+		b.WithDetailType(flags.detailType)
+	}
+	if flags.detail != "" {
+		if !json.Valid([]byte(flags.detail)) {
+			return nil, fmt.Errorf("-detail is not valid JSON: %s", flags.detail)
+		}
+		b.WithDetail(json.RawMessage(flags.detail))
+	}
+
+	return b.Build(), nil
+}
+
+func generateAPIGateway(flags generateFlags) (any, error) {
+	b := eventfixtures.APIGateway().WithMethod(flags.method).WithPath(flags.path)
+	if flags.body != "" {
+		b.WithBody(flags.body, false).WithHeader("content-type", "application/json")
+	}
+
+	return b.Build(), nil
+}
+
+func generateAPIGatewayV2(flags generateFlags) (any, error) {
+	b := eventfixtures.APIGatewayV2().WithMethod(flags.method).WithPath(flags.path)
+	if flags.body != "" {
+		b.WithBody(flags.body, false).WithHeader("content-type", "application/json")
+	}
+
+	return b.Build(), nil
+}
+
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+
+	return value
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func Test_generateEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		event    any
+		wantCode int
+		want     string
+	}{
+		{name: "SQS", args: []string{"sqs", "-count", "2", "-body", "hi"}, event: &events.SQSEvent{}, want: `"body":"hi"`},
+		{name: "SNS", args: []string{"-compact", "sns"}, event: &events.SNSEvent{}, want: `"Message":"Hello from SNS!"`},
+		{name: "CloudWatch Event", args: []string{"cloudwatch-event", "-source", "app", "-detail", `{"id":1}`}, event: &events.CloudWatchEvent{}, want: `"detail":{"id":1}`},
+		{name: "API Gateway", args: []string{"apigateway", "-method", "post", "-path", "/users"}, event: &events.APIGatewayProxyRequest{}, want: `"httpMethod":"POST"`},
+		{name: "API Gateway V2", args: []string{"apigateway-v2", "-body", "{}"}, event: &events.APIGatewayV2HTTPRequest{}, want: `"rawPath":"/"`},
+		{name: "Invalid Detail", args: []string{"cloudwatch-event", "-detail", "{"}, wantCode: 1},
+		{name: "Unknown Type", args: []string{"s3"}, wantCode: 2},
+		{name: "No Type", args: nil, wantCode: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+
+			if code := generateEvent(append(tt.args, "-compact"), nil, stdout, stderr); code != tt.wantCode {
+				t.Fatalf("generateEvent() = %d, want %d, stderr:\n%s", code, tt.wantCode, stderr)
+			}
+			if tt.event == nil {
+				return
+			}
+
+			if err := json.Unmarshal(stdout.Bytes(), tt.event); err != nil {
+				t.Fatalf("generateEvent() printed invalid JSON: %v", err)
+			}
+			if !strings.Contains(stdout.String(), tt.want) {
+				t.Errorf("generateEvent() = %s, want it to contain %s", stdout, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/RileyMcCuen/llb/pkg/llbtest"
+)
+
+type (
+	invokeFlags struct {
+		event   string
+		timeout time.Duration
+		name    string
+		build   bool
+	}
+)
+
+const (
+	defaultInvokeTimeout = 3 * time.Second
+	defaultFunctionName  = "llb-local"
+
+	// stopGrace is how long the function may take to exit after SIGTERM before it is killed
+	stopGrace = 500 * time.Millisecond
+
+	envRuntimeApi      = "AWS_LAMBDA_RUNTIME_API"
+	envFunctionName    = "AWS_LAMBDA_FUNCTION_NAME"
+	envFunctionVersion = "AWS_LAMBDA_FUNCTION_VERSION"
+	envInitType        = "AWS_LAMBDA_INITIALIZATION_TYPE"
+
+	timeoutErrorType = "Sandbox.Timedout"
+	exitErrorType    = "Runtime.ExitError"
+)
+
+// invoke runs a function against an emulated Runtime API with one event and prints its response, an error payload is printed as JSON and exits with 1
+func invoke(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := invokeFlags{}
+	fs := flag.NewFlagSet("invoke", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&flags.event, "event", "-", `file with the event payload, "-" reads it from stdin`)
+	fs.DurationVar(&flags.timeout, "timeout", defaultInvokeTimeout, "how long the function may run, it sets the invocation deadline")
+	fs.StringVar(&flags.name, "name", defaultFunctionName, "function name passed in "+envFunctionName)
+	fs.BoolVar(&flags.build, "build", false, "build the target with go build even if it is not a directory")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: llb invoke [flags] <binary | package directory> [-- function args]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	target, fnArgs := fs.Arg(0), fs.Args()[1:]
+	if len(fnArgs) > 0 && fnArgs[0] == "--" {
+		fnArgs = fnArgs[1:]
+	}
+
+	payload, err := readEvent(flags.event, stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "llb invoke:", err)
+		return 1
+	}
+
+	binary, cleanup, err := resolveBinary(target, flags.build, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, "llb invoke:", err)
+		return 1
+	}
+	defer cleanup()
+
+	result, err := runInvocation(binary, fnArgs, flags, payload, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, "llb invoke:", err)
+		return 1
+	}
+
+	return printResult(result, stdout)
+}
+
+func readEvent(path string, stdin io.Reader) ([]byte, error) {
+	if path == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("%w; reading the event from stdin", err)
+		}
+		return data, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w; reading the event file", err)
+	}
+
+	return data, nil
+}
+
+// resolveBinary returns the executable to run for target, a directory or a target with build set is compiled to a temporary directory removed by cleanup
+func resolveBinary(target string, build bool, stderr io.Writer) (string, func(), error) {
+	info, err := os.Stat(target)
+	if err != nil && !build {
+		return "", func() {}, err
+	}
+	if !build && !info.IsDir() {
+		path, err := filepath.Abs(target)
+		return path, func() {}, err
+	}
+
+	dir, err := os.MkdirTemp("", "llb-invoke-")
+	if err != nil {
+		return "", func() {}, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	binary := filepath.Join(dir, "bootstrap")
+	if info != nil && info.IsDir() && !strings.HasPrefix(target, ".") && !filepath.IsAbs(target) {
+		// go build reads a bare directory name as an import path
+		target = "./" + target
+	}
+
+	cmd := exec.Command("go", "build", "-o", binary, target)
+	cmd.Stdout, cmd.Stderr = stderr, stderr
+	if err := cmd.Run(); err != nil {
+		cleanup()
+		return "", func() {}, fmt.Errorf("%w; go build %s", err, target)
+	}
+
+	return binary, cleanup, nil
+}
+
+// runInvocation starts binary against a Runtime API serving payload and waits for its result until the deadline, the function output goes to stderr so stdout only has the result
+func runInvocation(binary string, args []string, flags invokeFlags, payload []byte, stderr io.Writer) (llbtest.Result, error) {
+	server := llbtest.NewServer()
+	defer server.Close()
+
+	deadline := time.Now().Add(flags.timeout)
+	id := server.Enqueue(llbtest.Event{Payload: payload, Deadline: deadline})[0]
+
+	cmd := exec.Command(binary, args...)
+	cmd.Stdout, cmd.Stderr = stderr, stderr
+	cmd.Env = append(os.Environ(),
+		envRuntimeApi+"="+server.Endpoint(),
+		envFunctionName+"="+flags.name,
+		envFunctionVersion+"=$LATEST",
+		envInitType+"=on-demand",
+	)
+
+	if err := cmd.Start(); err != nil {
+		return llbtest.Result{}, fmt.Errorf("%w; starting %s", err, binary)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	defer stopProcess(cmd, exited)
+
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	waited := make(chan error, 1)
+	var result llbtest.Result
+	go func() {
+		var err error
+		result, err = server.Wait(ctx, id)
+		waited <- err
+	}()
+
+	select {
+	case err := <-waited:
+		if errors.Is(err, context.DeadlineExceeded) {
+			return timeoutResult(id, flags.timeout), nil
+		}
+		return result, err
+	case err := <-exited:
+		// stopProcess waits for the exit again
+		exited <- err
+
+		// a result posted right before exiting still counts
+		if result, ok := server.Result(id); ok {
+			return result, nil
+		}
+		return exitResult(id, err), nil
+	}
+}
+
+// stopProcess sends SIGTERM so shutdown hooks run and kills the process if it is still running after stopGrace
+func stopProcess(cmd *exec.Cmd, exited chan error) {
+	cmd.Process.Signal(syscall.SIGTERM)
+
+	select {
+	case <-exited:
+	case <-time.After(stopGrace):
+		cmd.Process.Kill()
+		<-exited
+	}
+}
+
+func timeoutResult(id string, timeout time.Duration) llbtest.Result {
+	return llbtest.Result{
+		RequestId: id,
+		ErrorType: timeoutErrorType,
+		Error: &llbtest.ErrorPayload{
+			Message:    fmt.Sprintf("Task timed out after %.2f seconds", timeout.Seconds()),
+			Type:       timeoutErrorType,
+			StackTrace: []string{},
+		},
+	}
+}
+
+func exitResult(id string, err error) llbtest.Result {
+	message := "Runtime exited without providing a reason"
+	if err != nil {
+		message = "Runtime exited with error: " + err.Error()
+	}
+
+	return llbtest.Result{
+		RequestId: id,
+		ErrorType: exitErrorType,
+		Error: &llbtest.ErrorPayload{
+			Message:    message,
+			Type:       exitErrorType,
+			StackTrace: []string{},
+		},
+	}
+}
+
+// printResult writes the response or the indented error payload to w and returns the exit code
+func printResult(result llbtest.Result, w io.Writer) int {
+	if result.Error == nil {
+		w.Write(result.Response)
+		if len(result.Response) > 0 && result.Response[len(result.Response)-1] != '\n' {
+			fmt.Fprintln(w)
+		}
+		return 0
+	}
+
+	// a stream that failed part way has both a partial response and an error
+	if len(result.Response) > 0 {
+		w.Write(result.Response)
+		fmt.Fprintln(w)
+	}
+
+	data, _ := json.MarshalIndent(result.Error, "", "  ")
+	fmt.Fprintln(w, string(data))
+	return 1
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RileyMcCuen/llb"
+)
+
+const (
+	envTestFunction = "LLB_INVOKE_TEST_FUNCTION"
+)
+
+// TestMain runs the test binary as the invoked function when envTestFunction is set
+func TestMain(m *testing.M) {
+	switch os.Getenv(envTestFunction) {
+	case "":
+		os.Exit(m.Run())
+	case "echo":
+		llb.Start(func(ctx context.Context, r io.Reader) (io.Reader, error) {
+			if llb.MustFunctionInfo(ctx).Name != "local-fn" {
+				return nil, errors.New("function name was not set")
+			}
+			return r, nil
+		})
+	case "error":
+		llb.StartWithOptions(func(ctx context.Context, r io.Reader) (io.Reader, error) {
+			return nil, llb.NewError(errors.New("bad event"), "Function.BadEvent", "Function.BadEvent")
+		}, llb.WithFatal(func(error) {}))
+	case "sleep":
+		llb.Start(func(ctx context.Context, r io.Reader) (io.Reader, error) {
+			time.Sleep(time.Minute)
+			return nil, nil
+		})
+	case "exit":
+		os.Exit(3)
+	}
+}
+
+func Test_invoke(t *testing.T) {
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		function   string
+		args       []string
+		stdin      string
+		wantCode   int
+		wantStdout string
+	}{
+		{name: "Echo Stdin", function: "echo", args: []string{"-name", "local-fn"}, stdin: `{"key":"value"}`, wantStdout: `{"key":"value"}`},
+		{name: "Error Payload", function: "error", wantCode: 1, wantStdout: `"errorType": "Function.BadEvent"`},
+		{name: "Timeout", function: "sleep", args: []string{"-timeout", "200ms"}, wantCode: 1, wantStdout: "Task timed out after 0.20 seconds"},
+		{name: "Exit", function: "exit", wantCode: 1, wantStdout: "Runtime.ExitError"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envTestFunction, tt.function)
+			stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+
+			args := append(append([]string{}, tt.args...), binary)
+			if code := invoke(args, strings.NewReader(tt.stdin), stdout, stderr); code != tt.wantCode {
+				t.Errorf("invoke() = %d, want %d, stderr:\n%s", code, tt.wantCode, stderr)
+			}
+			if !strings.Contains(stdout.String(), tt.wantStdout) {
+				t.Errorf("invoke() stdout = %q, want it to contain %q", stdout, tt.wantStdout)
+			}
+		})
+	}
+}
+
+func Test_invoke_eventFile(t *testing.T) {
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(envTestFunction, "echo")
+
+	event := filepath.Join(t.TempDir(), "event.json")
+	os.WriteFile(event, []byte(`"from file"`), 0o600)
+
+	stdout := bytes.NewBuffer(nil)
+	if code := invoke([]string{"-name", "local-fn", "-event", event, binary}, strings.NewReader(""), stdout, io.Discard); code != 0 || strings.TrimSpace(stdout.String()) != `"from file"` {
+		t.Errorf("invoke() = %d, stdout %q", code, stdout)
+	}
+}
+
+func Test_invoke_build(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a function with go build")
+	}
+
+	stdout := bytes.NewBuffer(nil)
+	if code := invoke([]string{"testdata/echo"}, strings.NewReader(`"built"`), stdout, io.Discard); code != 0 || strings.TrimSpace(stdout.String()) != `"built"` {
+		t.Errorf("invoke() = %d, stdout %q", code, stdout)
+	}
+}
+
+func Test_readEvent(t *testing.T) {
+	if _, err := readEvent(filepath.Join(t.TempDir(), "missing.json"), nil); err == nil {
+		t.Error("readEvent() did not fail for a missing file")
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/RileyMcCuen/llb"
+)
+
+type (
+	command struct {
+		name    string
+		summary string
+		run     func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+	}
+)
+
+var (
+	commands = []command{
+		{name: "invoke", summary: "run a function binary locally against an emulated Runtime API", run: invoke},
+		{name: "generate-event", summary: "print a sample event for a handlerutil event type", run: generateEvent},
+	}
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run dispatches args to a subcommand and returns the exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], stdin, stdout, stderr)
+		}
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return 0
+	case "version", "-version", "--version":
+		fmt.Fprintln(stdout, "llb", llb.Version)
+		return 0
+	}
+
+	fmt.Fprintf(stderr, "llb: unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: llb <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `run "llb <command> -h" for the flags of a command`)
+}
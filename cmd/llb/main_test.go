@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/RileyMcCuen/llb"
+)
+
+func Test_run(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{name: "No Command", args: nil, wantCode: 2, wantStderr: "usage: llb"},
+		{name: "Unknown Command", args: []string{"deploy"}, wantCode: 2, wantStderr: `unknown command "deploy"`},
+		{name: "Help", args: []string{"help"}, wantCode: 0, wantStdout: "invoke"},
+		{name: "Version", args: []string{"version"}, wantCode: 0, wantStdout: llb.Version},
+		{name: "Invoke Without Target", args: []string{"invoke"}, wantCode: 2, wantStderr: "usage: llb invoke"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+
+			if code := run(tt.args, strings.NewReader(""), stdout, stderr); code != tt.wantCode {
+				t.Errorf("run() = %d, want %d", code, tt.wantCode)
+			}
+			if !strings.Contains(stdout.String(), tt.wantStdout) {
+				t.Errorf("run() stdout = %q, want it to contain %q", stdout, tt.wantStdout)
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("run() stderr = %q, want it to contain %q", stderr, tt.wantStderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"context"
+	"io"
+
+	"github.com/RileyMcCuen/llb"
+)
+
+func main() {
+	llb.Start(func(ctx context.Context, r io.Reader) (io.Reader, error) {
+		return r, nil
+	})
+}
@@ -2,25 +2,92 @@ package llb
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
 	"time"
 )
 
 type (
-	requestMetaContextKey struct{}
-	RequestMeta           struct {
+	requestMetaContextKey  struct{}
+	functionInfoContextKey struct{}
+
+	// FunctionInfo describes the function being run, it is read once from the environment when the runtime starts
+	FunctionInfo struct {
+		Name               string
+		Version            string
+		MemorySize         int
+		LogGroupName       string
+		LogStreamName      string
+		Region             string
+		InitializationType string
+		Handler            string
+	}
+	RequestMeta struct {
 		TraceId         string
+		Trace           TraceHeader
 		RequestId       string
 		Deadline        time.Time
 		LambdaArn       string
 		ClientContext   string
 		CognitoIdentity string
+
+		// ColdStart is set for the first invocation served by the runtime
+		ColdStart bool
+		// InitDuration is how long the StartWithInit init function took, it is only set when ColdStart is
+		InitDuration time.Duration
+	}
+
+	// ClientApplication, ClientContext and CognitoIdentity have the fields of their aws-lambda-go lambdacontext counterparts
+	ClientApplication struct {
+		InstallationID string `json:"installation_id"`
+		AppTitle       string `json:"app_title"`
+		AppVersionCode string `json:"app_version_code"`
+		AppPackageName string `json:"app_package_name"`
+	}
+	ClientContext struct {
+		Client ClientApplication `json:"client"`
+		Env    map[string]string `json:"env"`
+		Custom map[string]string `json:"custom"`
 	}
+	CognitoIdentity struct {
+		CognitoIdentityID     string `json:"cognitoIdentityId"`
+		CognitoIdentityPoolID string `json:"cognitoIdentityPoolId"`
+	}
+)
+
+const (
+	envFunctionName       = "AWS_LAMBDA_FUNCTION_NAME"
+	envFunctionVersion    = "AWS_LAMBDA_FUNCTION_VERSION"
+	envFunctionMemorySize = "AWS_LAMBDA_FUNCTION_MEMORY_SIZE"
+	envLogGroupName       = "AWS_LAMBDA_LOG_GROUP_NAME"
+	envLogStreamName      = "AWS_LAMBDA_LOG_STREAM_NAME"
+	envRegion             = "AWS_REGION"
+	envDefaultRegion      = "AWS_DEFAULT_REGION"
+	envInitializationType = "AWS_LAMBDA_INITIALIZATION_TYPE"
+	envHandler            = "_HANDLER"
 )
 
 var (
-	contextKey = requestMetaContextKey{}
+	contextKey      = requestMetaContextKey{}
+	functionInfoKey = functionInfoContextKey{}
+
+	ErrNoClientContext   = errors.New("invocation has no client context")
+	ErrNoCognitoIdentity = errors.New("invocation has no cognito identity")
 )
 
+// ContextWithRequestMeta returns a copy of ctx carrying meta, the runtime does this for every invocation; it is useful to call handlers in tests
+func ContextWithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, contextKey, meta)
+}
+
+// ContextWithFunctionInfo returns a copy of ctx carrying info
+func ContextWithFunctionInfo(ctx context.Context, info FunctionInfo) context.Context {
+	return context.WithValue(ctx, functionInfoKey, info)
+}
+
 func GetRequestMeta(ctx context.Context) (RequestMeta, bool) {
 	raw := ctx.Value(contextKey)
 	if raw == nil {
@@ -33,3 +100,78 @@ func GetRequestMeta(ctx context.Context) (RequestMeta, bool) {
 func MustRequestMeta(ctx context.Context) RequestMeta {
 	return ctx.Value(contextKey).(RequestMeta)
 }
+
+func GetFunctionInfo(ctx context.Context) (FunctionInfo, bool) {
+	raw := ctx.Value(functionInfoKey)
+	if raw == nil {
+		return FunctionInfo{}, false
+	}
+
+	return raw.(FunctionInfo), true
+}
+
+func MustFunctionInfo(ctx context.Context) FunctionInfo {
+	return ctx.Value(functionInfoKey).(FunctionInfo)
+}
+
+// RemainingTime returns the time left until the invocation deadline in RequestMeta, it is zero when ctx has no RequestMeta or the deadline has passed
+func RemainingTime(ctx context.Context) time.Duration {
+	meta, ok := GetRequestMeta(ctx)
+	if !ok {
+		return 0
+	}
+
+	if remaining := time.Until(meta.Deadline); remaining > 0 {
+		return remaining
+	}
+
+	return 0
+}
+
+func functionInfoFromEnv() FunctionInfo {
+	memorySize, _ := strconv.Atoi(os.Getenv(envFunctionMemorySize))
+
+	region := os.Getenv(envRegion)
+	if region == "" {
+		region = os.Getenv(envDefaultRegion)
+	}
+
+	return FunctionInfo{
+		Name:               os.Getenv(envFunctionName),
+		Version:            os.Getenv(envFunctionVersion),
+		MemorySize:         memorySize,
+		LogGroupName:       os.Getenv(envLogGroupName),
+		LogStreamName:      os.Getenv(envLogStreamName),
+		Region:             region,
+		InitializationType: os.Getenv(envInitializationType),
+		Handler:            os.Getenv(envHandler),
+	}
+}
+
+// ParseClientContext parses the Lambda-Runtime-Client-Context header, ErrNoClientContext is returned when the invocation did not have one
+func (meta RequestMeta) ParseClientContext() (ClientContext, error) {
+	if meta.ClientContext == "" {
+		return ClientContext{}, ErrNoClientContext
+	}
+
+	clientContext := ClientContext{}
+	if err := json.Unmarshal([]byte(meta.ClientContext), &clientContext); err != nil {
+		return ClientContext{}, fmt.Errorf("%w; RequestMeta.ParseClientContext header %s is not valid JSON", err, headerClientContext)
+	}
+
+	return clientContext, nil
+}
+
+// ParseCognitoIdentity parses the Lambda-Runtime-Cognito-Identity header, ErrNoCognitoIdentity is returned when the invocation did not have one
+func (meta RequestMeta) ParseCognitoIdentity() (CognitoIdentity, error) {
+	if meta.CognitoIdentity == "" {
+		return CognitoIdentity{}, ErrNoCognitoIdentity
+	}
+
+	identity := CognitoIdentity{}
+	if err := json.Unmarshal([]byte(meta.CognitoIdentity), &identity); err != nil {
+		return CognitoIdentity{}, fmt.Errorf("%w; RequestMeta.ParseCognitoIdentity header %s is not valid JSON", err, headerCognitoIdentity)
+	}
+
+	return identity, nil
+}
@@ -2,7 +2,11 @@ package llb
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestGetRequestMetaSuccess(t *testing.T) {
@@ -44,3 +48,140 @@ func TestMustRequestMetaFailure(t *testing.T) {
 	ctx := context.Background()
 	_ = MustRequestMeta(ctx)
 }
+
+func TestRequestMetaParseClientContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    ClientContext
+		wantErr error
+	}{
+		{
+			name:   "Success",
+			header: `{"client":{"installation_id":"install","app_title":"title","app_version_code":"1","app_package_name":"pkg"},"env":{"platform":"ios"},"custom":{"key":"value"}}`,
+			want: ClientContext{
+				Client: ClientApplication{InstallationID: "install", AppTitle: "title", AppVersionCode: "1", AppPackageName: "pkg"},
+				Env:    map[string]string{"platform": "ios"},
+				Custom: map[string]string{"key": "value"},
+			},
+		},
+		{name: "Missing", header: "", wantErr: ErrNoClientContext},
+		{name: "Malformed", header: "{", wantErr: &json.SyntaxError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RequestMeta{ClientContext: tt.header}.ParseClientContext()
+			checkParseError(t, err, tt.wantErr)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RequestMeta.ParseClientContext() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestMetaParseCognitoIdentity(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    CognitoIdentity
+		wantErr error
+	}{
+		{
+			name:   "Success",
+			header: `{"cognitoIdentityId":"id","cognitoIdentityPoolId":"pool"}`,
+			want:   CognitoIdentity{CognitoIdentityID: "id", CognitoIdentityPoolID: "pool"},
+		},
+		{name: "Missing", header: "", wantErr: ErrNoCognitoIdentity},
+		{name: "Malformed", header: "{", wantErr: &json.SyntaxError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RequestMeta{CognitoIdentity: tt.header}.ParseCognitoIdentity()
+			checkParseError(t, err, tt.wantErr)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RequestMeta.ParseCognitoIdentity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func checkParseError(t *testing.T, err, want error) {
+	t.Helper()
+
+	var syntaxErr *json.SyntaxError
+	switch {
+	case want == nil && err != nil:
+		t.Fatalf("unexpected error %v", err)
+	case errors.As(want, &syntaxErr) && !errors.As(err, &syntaxErr):
+		t.Fatalf("error = %v, want a JSON syntax error", err)
+	case want != nil && !errors.As(want, &syntaxErr) && !errors.Is(err, want):
+		t.Fatalf("error = %v, want %v", err, want)
+	}
+}
+
+func TestGetFunctionInfo(t *testing.T) {
+	ctx := context.WithValue(context.Background(), functionInfoKey, FunctionInfo{Name: "fn"})
+	info, ok := GetFunctionInfo(ctx)
+	if !ok || info.Name != "fn" {
+		t.Fatal("GetFunctionInfo returned wrong FunctionInfo")
+	}
+
+	if _, ok := GetFunctionInfo(context.Background()); ok {
+		t.Fatal("GetFunctionInfo reported ok when there was no FunctionInfo")
+	}
+
+	if MustFunctionInfo(ctx).Name != "fn" {
+		t.Fatal("MustFunctionInfo returned wrong FunctionInfo")
+	}
+}
+
+func TestRemainingTime(t *testing.T) {
+	ctx := context.WithValue(context.Background(), contextKey, RequestMeta{Deadline: time.Now().Add(time.Minute)})
+	if remaining := RemainingTime(ctx); remaining <= 59*time.Second || remaining > time.Minute {
+		t.Errorf("RemainingTime() = %v, want about a minute", remaining)
+	}
+
+	ctx = context.WithValue(context.Background(), contextKey, RequestMeta{Deadline: time.Now().Add(-time.Minute)})
+	if remaining := RemainingTime(ctx); remaining != 0 {
+		t.Errorf("RemainingTime() = %v after the deadline, want 0", remaining)
+	}
+
+	if remaining := RemainingTime(context.Background()); remaining != 0 {
+		t.Errorf("RemainingTime() = %v without RequestMeta, want 0", remaining)
+	}
+}
+
+func Test_functionInfoFromEnv(t *testing.T) {
+	t.Setenv(envFunctionName, "fn")
+	t.Setenv(envFunctionVersion, "$LATEST")
+	t.Setenv(envFunctionMemorySize, "128")
+	t.Setenv(envLogGroupName, "/aws/lambda/fn")
+	t.Setenv(envLogStreamName, "stream")
+	t.Setenv(envRegion, "")
+	t.Setenv(envDefaultRegion, "us-east-1")
+	t.Setenv(envInitializationType, "on-demand")
+	t.Setenv(envHandler, "bootstrap")
+
+	want := FunctionInfo{
+		Name:               "fn",
+		Version:            "$LATEST",
+		MemorySize:         128,
+		LogGroupName:       "/aws/lambda/fn",
+		LogStreamName:      "stream",
+		Region:             "us-east-1",
+		InitializationType: "on-demand",
+		Handler:            "bootstrap",
+	}
+	if got := functionInfoFromEnv(); !reflect.DeepEqual(got, want) {
+		t.Errorf("functionInfoFromEnv() = %v, want %v", got, want)
+	}
+}
+
+func TestContextWith(t *testing.T) {
+	ctx := ContextWithRequestMeta(context.Background(), RequestMeta{RequestId: "req"})
+	ctx = ContextWithFunctionInfo(ctx, FunctionInfo{Name: "fn"})
+
+	if MustRequestMeta(ctx).RequestId != "req" || MustFunctionInfo(ctx).Name != "fn" {
+		t.Fatal("ContextWithRequestMeta and ContextWithFunctionInfo did not store their values")
+	}
+}
@@ -2,7 +2,9 @@ package llb
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"time"
 )
 
 type (
@@ -15,6 +17,17 @@ type (
 		ContentType() string
 	}
 
+	streamingResponse struct {
+		stream      func(w io.Writer) error
+		contentType string
+		reader      *io.PipeReader
+	}
+	// StreamingResponse is a Response that is written to the Runtime API with Lambda response streaming, to create a conforming StreamingResponse use NewStreamingResponse
+	StreamingResponse interface {
+		Response
+		Stream(w io.Writer) error
+	}
+
 	defaultError struct {
 		error
 		header, typ string
@@ -25,6 +38,17 @@ type (
 		Type() string
 	}
 
+	// ResponseSizeError is reported in place of a handler response larger than MaxLambdaInvokeSize, Size is the full size of the response in bytes
+	ResponseSizeError struct {
+		Size, Limit int64
+	}
+
+	// TimeoutError is reported in place of the handler result when the handler returns after its context deadline was exceeded
+	TimeoutError struct {
+		Deadline time.Time
+		Err      error
+	}
+
 	/*
 		If a llb.Response is returned from handler, the extra response information will be passed on to the response endpoint, to create a conforming Response use NewResponse
 
@@ -32,12 +56,24 @@ type (
 	*/
 	Handler func(ctx context.Context, r io.Reader) (io.Reader, error)
 
+	// StreamingHandler writes its response to w while the invocation is streamed, ctx and r stay valid until it returns; convert it to a Handler with NewStreamingHandler
+	StreamingHandler func(ctx context.Context, r io.Reader, w io.Writer) error
+
 	ErrorHandler func(err error) (io.Reader, error)
+
+	// InitFunc runs once before the first invocation and returns the Handler to serve, if a llb.Error is returned its header and type are reported to the init error endpoint
+	InitFunc func(ctx context.Context) (Handler, error)
+
+	// ResponseOverflowHandler receives a complete handler response that is larger than MaxLambdaInvokeSize and returns the response to post instead, e.g. a pointer to the payload after offloading it to S3
+	ResponseOverflowHandler func(ctx context.Context, r io.Reader) (io.Reader, error)
 )
 
 var (
 	_ = Response(defaultReponse{})
+	_ = StreamingResponse(&streamingResponse{})
 	_ = Error(defaultError{})
+	_ = Error(TimeoutError{})
+	_ = Error(ResponseSizeError{})
 	_ = ErrorHandler(DefaultErrorHandler)
 )
 
@@ -50,8 +86,43 @@ func NewResponse(r io.Reader, contentType string) Response {
 	}
 }
 
+func (sr *streamingResponse) ContentType() string      { return sr.contentType }
+func (sr *streamingResponse) Stream(w io.Writer) error { return sr.stream(w) }
+
+// Read runs the stream into a pipe, so a StreamingResponse can still be consumed as a plain io.Reader
+func (sr *streamingResponse) Read(p []byte) (int, error) {
+	if sr.reader == nil {
+		pr, pw := io.Pipe()
+		sr.reader = pr
+
+		go func() {
+			pw.CloseWithError(sr.stream(pw))
+		}()
+	}
+
+	return sr.reader.Read(p)
+}
+
+// NewStreamingResponse creates a StreamingResponse, stream is called once with the writer connected to the Runtime API
+func NewStreamingResponse(stream func(w io.Writer) error, contentType string) StreamingResponse {
+	return &streamingResponse{
+		stream:      stream,
+		contentType: contentType,
+	}
+}
+
+// NewStreamingHandler creates a Handler that streams every response written by handler with the given content type
+func NewStreamingHandler(handler StreamingHandler, contentType string) Handler {
+	return func(ctx context.Context, r io.Reader) (io.Reader, error) {
+		return NewStreamingResponse(func(w io.Writer) error {
+			return handler(ctx, r, w)
+		}, contentType), nil
+	}
+}
+
 func (ce defaultError) Header() string { return ce.header }
 func (ce defaultError) Type() string   { return ce.typ }
+func (ce defaultError) Unwrap() error  { return ce.error }
 
 func NewError(err error, header, typ string) Error {
 	return defaultError{
@@ -61,4 +132,21 @@ func NewError(err error, header, typ string) Error {
 	}
 }
 
+func (te TimeoutError) Error() string {
+	if te.Err == nil {
+		return fmt.Sprintf("handler exceeded invocation deadline %s", te.Deadline.Format(time.RFC3339Nano))
+	}
+
+	return fmt.Sprintf("handler exceeded invocation deadline %s: %s", te.Deadline.Format(time.RFC3339Nano), te.Err)
+}
+func (te TimeoutError) Unwrap() error  { return te.Err }
+func (te TimeoutError) Header() string { return timeoutErrorHeader }
+func (te TimeoutError) Type() string   { return timeoutErrorHeader }
+
+func (rse ResponseSizeError) Error() string {
+	return fmt.Sprintf("response size %d bytes exceeds the maximum of %d bytes", rse.Size, rse.Limit)
+}
+func (rse ResponseSizeError) Header() string { return responseSizeErrorHeader }
+func (rse ResponseSizeError) Type() string   { return responseSizeErrorHeader }
+
 func DefaultErrorHandler(err error) (io.Reader, error) { return nil, err }
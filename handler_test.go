@@ -1,8 +1,12 @@
 package llb
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"io"
 	"testing"
+	"time"
 )
 
 func TestNewResponse(t *testing.T) {
@@ -28,3 +32,46 @@ func TestDefaultErrorHandler(t *testing.T) {
 		t.Fatal("DefaultErrorHandler did not preserve error message")
 	}
 }
+
+func TestTimeoutError(t *testing.T) {
+	cause := errors.New("cause")
+	err := TimeoutError{Deadline: time.UnixMilli(100), Err: cause}
+	if !errors.Is(err, cause) {
+		t.Fatal("TimeoutError did not unwrap to its cause")
+	}
+	if err.Header() != timeoutErrorHeader || err.Type() != timeoutErrorHeader {
+		t.Fatal("TimeoutError did not report the timeout error type")
+	}
+}
+
+func TestNewStreamingHandler(t *testing.T) {
+	handler := NewStreamingHandler(func(ctx context.Context, r io.Reader, w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	}, "text/plain")
+
+	out, err := handler(context.Background(), bytes.NewBufferString("data"))
+	if err != nil {
+		t.Fatal("NewStreamingHandler returned an error but should not have")
+	}
+
+	resp, ok := out.(StreamingResponse)
+	if !ok {
+		t.Fatal("NewStreamingHandler did not return a StreamingResponse")
+	}
+	if resp.ContentType() != "text/plain" {
+		t.Fatal("NewStreamingHandler did not preserve the content type passed in")
+	}
+
+	data, err := io.ReadAll(resp)
+	if err != nil || string(data) != "data" {
+		t.Fatalf("StreamingResponse read = %q, %v", data, err)
+	}
+}
+
+func TestStreamingResponseReadError(t *testing.T) {
+	resp := NewStreamingResponse(func(w io.Writer) error { return errors.New("test") }, "text/plain")
+	if _, err := io.ReadAll(resp); err == nil || err.Error() != "test" {
+		t.Fatal("StreamingResponse did not surface the stream error when read")
+	}
+}
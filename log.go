@@ -0,0 +1,124 @@
+package llb
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type (
+	loggerContextKey struct{}
+)
+
+const (
+	// LevelTrace and LevelFatal complete the slog levels with the two extra Lambda log levels
+	LevelTrace = slog.LevelDebug - 4
+	LevelFatal = slog.LevelError + 4
+
+	envLogFormat = "AWS_LAMBDA_LOG_FORMAT"
+	envLogLevel  = "AWS_LAMBDA_LOG_LEVEL"
+
+	jsonLogFormat = "JSON"
+
+	logKeyTimestamp   = "timestamp"
+	logKeyMessage     = "message"
+	logKeyRequestId   = "requestId"
+	logKeyTraceId     = "traceId"
+	logKeyFunctionArn = "functionArn"
+)
+
+var (
+	loggerKey = loggerContextKey{}
+)
+
+// NewLogHandler returns a handler writing to w at level, it writes Lambda's advanced logging JSON format when AWS_LAMBDA_LOG_FORMAT is JSON and text otherwise
+func NewLogHandler(w io.Writer, level slog.Leveler) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: replaceLevelAttr}
+
+	if strings.EqualFold(os.Getenv(envLogFormat), jsonLogFormat) {
+		opts.ReplaceAttr = replaceLambdaAttr
+		return slog.NewJSONHandler(w, opts)
+	}
+
+	return slog.NewTextHandler(w, opts)
+}
+
+// logLevelFromEnv reads AWS_LAMBDA_LOG_LEVEL, defaults to slog.LevelInfo
+func logLevelFromEnv() slog.Level {
+	switch strings.ToUpper(os.Getenv(envLogLevel)) {
+	case "TRACE":
+		return LevelTrace
+	case "DEBUG":
+		return slog.LevelDebug
+	case "WARN":
+		return slog.LevelWarn
+	case "ERROR":
+		return slog.LevelError
+	case "FATAL":
+		return LevelFatal
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// replaceLevelAttr names the TRACE and FATAL levels, slog would print them as DEBUG-4 and ERROR+4
+func replaceLevelAttr(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) > 0 || attr.Key != slog.LevelKey {
+		return attr
+	}
+
+	switch level, _ := attr.Value.Any().(slog.Level); level {
+	case LevelTrace:
+		attr.Value = slog.StringValue("TRACE")
+	case LevelFatal:
+		attr.Value = slog.StringValue("FATAL")
+	}
+
+	return attr
+}
+
+// replaceLambdaAttr renames the built in keys to the ones used by Lambda's JSON log format
+func replaceLambdaAttr(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return attr
+	}
+
+	switch attr.Key {
+	case slog.TimeKey:
+		attr.Key = logKeyTimestamp
+		attr.Value = slog.TimeValue(attr.Value.Time().UTC())
+	case slog.MessageKey:
+		attr.Key = logKeyMessage
+	}
+
+	return replaceLevelAttr(groups, attr)
+}
+
+// ContextWithLogger returns a copy of ctx carrying logger, it is returned by Logger
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// Logger returns the logger of the invocation in ctx, it carries the request id, trace id and function ARN; outside of an invocation slog.Default() is returned
+func Logger(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+
+	if meta, ok := GetRequestMeta(ctx); ok {
+		return withRequestMeta(slog.Default(), meta)
+	}
+
+	return slog.Default()
+}
+
+// withRequestMeta adds the request attributes of meta to logger
+func withRequestMeta(logger *slog.Logger, meta RequestMeta) *slog.Logger {
+	return logger.With(
+		slog.String(logKeyRequestId, meta.RequestId),
+		slog.String(logKeyTraceId, meta.TraceId),
+		slog.String(logKeyFunctionArn, meta.LambdaArn),
+	)
+}
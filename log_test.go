@@ -0,0 +1,110 @@
+package llb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewLogHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		want   []string
+	}{
+		{"Text", "", []string{"level=INFO", "msg=hello", "requestId=req"}},
+		{"Text Format", "Text", []string{"level=INFO", "msg=hello", "requestId=req"}},
+		{"JSON", "JSON", []string{`"timestamp":`, `"level":"INFO"`, `"message":"hello"`, `"requestId":"req"`, `"traceId":"trace"`, `"functionArn":"arn"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envLogFormat, tt.format)
+			buf := bytes.NewBuffer(nil)
+
+			logger := slog.New(NewLogHandler(buf, slog.LevelInfo))
+			withRequestMeta(logger, RequestMeta{RequestId: "req", TraceId: "trace", LambdaArn: "arn"}).Info("hello")
+
+			out := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("NewLogHandler() logged %q, want it to contain %q", out, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewLogHandler_json(t *testing.T) {
+	t.Setenv(envLogFormat, "JSON")
+	buf := bytes.NewBuffer(nil)
+
+	slog.New(NewLogHandler(buf, LevelTrace)).Log(context.Background(), LevelTrace, "trace")
+
+	record := map[string]any{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("NewLogHandler() logged invalid JSON %q: %v", buf.String(), err)
+	}
+	if record["level"] != "TRACE" {
+		t.Errorf("NewLogHandler() level = %v, want TRACE", record["level"])
+	}
+	if _, ok := record["time"]; ok {
+		t.Error("NewLogHandler() did not rename the time key")
+	}
+}
+
+func Test_logLevelFromEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		want slog.Level
+	}{
+		{"Unset", "", slog.LevelInfo},
+		{"Trace", "TRACE", LevelTrace},
+		{"Debug", "debug", slog.LevelDebug},
+		{"Warn", "WARN", slog.LevelWarn},
+		{"Error", "ERROR", slog.LevelError},
+		{"Fatal", "FATAL", LevelFatal},
+		{"Invalid", "LOUD", slog.LevelInfo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envLogLevel, tt.env)
+
+			if got := logLevelFromEnv(); got != tt.want {
+				t.Errorf("logLevelFromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+
+	if got := Logger(context.Background()); got != slog.Default() {
+		t.Error("Logger() without an invocation did not return slog.Default()")
+	}
+	if got := Logger(ContextWithLogger(context.Background(), logger)); got != logger {
+		t.Error("Logger() did not return the logger in the context")
+	}
+	if got := Logger(ContextWithRequestMeta(context.Background(), RequestMeta{RequestId: "req"})); got == slog.Default() {
+		t.Error("Logger() did not add the RequestMeta attributes")
+	}
+}
+
+func Test_runtime_context_logger(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	rt := newRuntime(nil, nil, nil)
+	rt.logger = slog.New(slog.NewTextHandler(buf, nil))
+	rt.meta = RequestMeta{RequestId: "req", TraceId: "trace", LambdaArn: "arn"}
+
+	ctx, cancel := rt.context()
+	defer cancel()
+	Logger(ctx).Info("hello")
+
+	if out := buf.String(); !strings.Contains(out, "requestId=req traceId=trace functionArn=arn") {
+		t.Errorf("runtime.context() logger logged %q", out)
+	}
+}
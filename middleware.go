@@ -0,0 +1,99 @@
+package llb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+type (
+	// Middleware wraps a Handler, it works the same for raw handlers and those built by handlerutil
+	Middleware func(Handler) Handler
+)
+
+const (
+	requestSizeErrorHeader = "Function.RequestTooLarge"
+)
+
+// Chain composes middlewares into one, the first middleware is the outermost
+func Chain(middlewares ...Middleware) Middleware {
+	return func(handler Handler) Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			handler = middlewares[i](handler)
+		}
+
+		return handler
+	}
+}
+
+// LoggingMiddleware logs the start and result of every invocation with the request logger from Logger
+func LoggingMiddleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, r io.Reader) (io.Reader, error) {
+			meta, _ := GetRequestMeta(ctx)
+			logger := Logger(ctx)
+			logger.Info("invocation started", "coldStart", meta.ColdStart)
+
+			start := time.Now()
+			out, err := next(ctx, r)
+
+			if err != nil {
+				logger.Error("invocation failed", "duration", time.Since(start), "error", err)
+			} else {
+				logger.Info("invocation finished", "duration", time.Since(start))
+			}
+
+			return out, err
+		}
+	}
+}
+
+// TimingMiddleware calls record with the duration of every invocation
+func TimingMiddleware(record func(ctx context.Context, duration time.Duration)) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, r io.Reader) (io.Reader, error) {
+			start := time.Now()
+			out, err := next(ctx, r)
+			record(ctx, time.Since(start))
+
+			return out, err
+		}
+	}
+}
+
+// RecoverMiddleware converts a panic in the wrapped handler into a Runtime.HandlerPanic Error with the stack of the panic, like a panic recovered by the runtime it is reported for the invocation and the runtime keeps serving
+func RecoverMiddleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, r io.Reader) (out io.Reader, err error) {
+			defer func() {
+				if v := recover(); v != nil {
+					out, err = nil, newHandlerPanic(v)
+				}
+			}()
+
+			return next(ctx, r)
+		}
+	}
+}
+
+// MaxRequestSizeMiddleware rejects requests larger than limit bytes with a Function.RequestTooLarge Error without calling the wrapped handler
+func MaxRequestSizeMiddleware(limit int64) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, r io.Reader) (io.Reader, error) {
+			buf := bytes.NewBuffer(nil)
+			n, err := buf.ReadFrom(io.LimitReader(r, limit+1))
+			if err != nil {
+				return nil, fmt.Errorf("%w; MaxRequestSizeMiddleware failed reading the request", err)
+			}
+
+			if n > limit {
+				err := fmt.Errorf("request payload exceeds the maximum of %d bytes", limit)
+				return nil, NewError(err, requestSizeErrorHeader, requestSizeErrorHeader)
+			}
+
+			return next(ctx, buf)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package llb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChain(t *testing.T) {
+	calls := []string{}
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, r io.Reader) (io.Reader, error) {
+				calls = append(calls, name)
+				return next(ctx, r)
+			}
+		}
+	}
+
+	handler := Chain(trace("first"), trace("second"))(func(ctx context.Context, r io.Reader) (io.Reader, error) {
+		calls = append(calls, "handler")
+		return nil, nil
+	})
+	handler(context.Background(), nil)
+
+	if want := []string{"first", "second", "handler"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("Chain() call order = %v, want %v", calls, want)
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	ctx := ContextWithLogger(context.Background(), withRequestMeta(slog.New(slog.NewTextHandler(buf, nil)), RequestMeta{RequestId: "req"}))
+
+	handler := LoggingMiddleware()(func(ctx context.Context, r io.Reader) (io.Reader, error) {
+		return nil, errors.New("failed")
+	})
+	handler(ctx, nil)
+
+	if out := buf.String(); !strings.Contains(out, "requestId=req") || !strings.Contains(out, "error=failed") {
+		t.Errorf("LoggingMiddleware logged %q", out)
+	}
+}
+
+func TestTimingMiddleware(t *testing.T) {
+	var recorded time.Duration
+	handler := TimingMiddleware(func(ctx context.Context, d time.Duration) { recorded = d })(func(ctx context.Context, r io.Reader) (io.Reader, error) {
+		time.Sleep(time.Millisecond)
+		return nil, nil
+	})
+	handler(context.Background(), nil)
+
+	if recorded < time.Millisecond {
+		t.Errorf("TimingMiddleware recorded %v", recorded)
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	handler := RecoverMiddleware()(func(ctx context.Context, r io.Reader) (io.Reader, error) {
+		panic("boom")
+	})
+
+	_, err := handler(context.Background(), nil)
+
+	var llbErr Error
+	if !errors.As(err, &llbErr) || llbErr.Type() != handlerPanicHeader || err.Error() != "panic: boom" {
+		t.Fatalf("RecoverMiddleware returned %v, want %s", err, handlerPanicHeader)
+	}
+	if trace := err.(StackTracer).StackTrace(); len(trace) == 0 || !strings.Contains(trace[0], "TestRecoverMiddleware") {
+		t.Errorf("RecoverMiddleware stack trace does not start at the panic: %v", trace)
+	}
+}
+
+func TestMaxRequestSizeMiddleware(t *testing.T) {
+	handler := MaxRequestSizeMiddleware(4)(func(ctx context.Context, r io.Reader) (io.Reader, error) {
+		return r, nil
+	})
+
+	out, err := handler(context.Background(), bytes.NewBufferString("data"))
+	if data, _ := io.ReadAll(out); err != nil || string(data) != "data" {
+		t.Errorf("MaxRequestSizeMiddleware within limit = %q, %v", data, err)
+	}
+
+	_, err = handler(context.Background(), bytes.NewBufferString("data!"))
+	var llbErr Error
+	if !errors.As(err, &llbErr) || llbErr.Type() != requestSizeErrorHeader {
+		t.Errorf("MaxRequestSizeMiddleware over limit = %v, want %s", err, requestSizeErrorHeader)
+	}
+}
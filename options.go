@@ -0,0 +1,181 @@
+package llb
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+type (
+	// Option configures the runtime started by StartWithOptions
+	Option func(*config)
+
+	// Hooks are called by the runtime around every invocation, nil fields are skipped
+	Hooks struct {
+		// BeforeNext is called before the runtime polls for the next invocation
+		BeforeNext func()
+		// BeforeInvoke is called before the handler runs, the returned context is passed to the handler and to AfterInvoke
+		BeforeInvoke func(ctx context.Context) context.Context
+		// AfterInvoke is called after the handler result has been posted, err is the error reported for the invocation if any
+		AfterInvoke func(ctx context.Context, err error)
+	}
+
+	config struct {
+		client         httpClient
+		endpoint       string
+		fatal          func(error)
+		logger         *slog.Logger
+		logHandler     slog.Handler
+		logLevel       slog.Leveler
+		hooks          []Hooks
+		deadlineMargin time.Duration
+		crashOnPanic   bool
+		overflow       ResponseOverflowHandler
+		traceEnvOff    bool
+		retry          RetryPolicy
+		maxInvocations int
+
+		shutdownHooks   []func(ctx context.Context)
+		shutdownTimeout time.Duration
+	}
+)
+
+func newConfig(opts []Option) config {
+	cfg := config{
+		client:         http.DefaultClient,
+		endpoint:       os.Getenv(envRuntimeDomain),
+		fatal:          defaultFatal,
+		logLevel:       logLevelFromEnv(),
+		retry:          DefaultRetryPolicy(),
+		deadlineMargin: deadlineMarginFromEnv(),
+
+		shutdownTimeout: DefaultShutdownTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.logger == nil {
+		if cfg.logHandler == nil {
+			cfg.logHandler = NewLogHandler(os.Stdout, cfg.logLevel)
+		}
+		cfg.logger = slog.New(cfg.logHandler)
+	}
+
+	return cfg
+}
+
+// WithHTTPClient sets the client used to call the Runtime API, defaults to http.DefaultClient
+func WithHTTPClient(client *http.Client) Option {
+	return func(cfg *config) {
+		cfg.client = client
+	}
+}
+
+// WithTransport calls the Runtime API through a client using transport
+func WithTransport(transport http.RoundTripper) Option {
+	return func(cfg *config) {
+		cfg.client = &http.Client{Transport: transport}
+	}
+}
+
+// WithRuntimeAPI sets the Runtime API host and port, defaults to the AWS_LAMBDA_RUNTIME_API environment variable
+func WithRuntimeAPI(endpoint string) Option {
+	return func(cfg *config) {
+		cfg.endpoint = endpoint
+	}
+}
+
+// WithFatal sets the function called when the runtime loop fails, defaults to panicking with the error
+func WithFatal(fatal func(error)) Option {
+	return func(cfg *config) {
+		cfg.fatal = fatal
+	}
+}
+
+// WithLogger sets the logger used by the runtime, it takes precedence over WithLogHandler and WithLogLevel
+func WithLogger(logger *slog.Logger) Option {
+	return func(cfg *config) {
+		cfg.logger = logger
+	}
+}
+
+// WithLogHandler sets the handler of the runtime logger, defaults to NewLogHandler writing to stdout
+func WithLogHandler(handler slog.Handler) Option {
+	return func(cfg *config) {
+		cfg.logHandler = handler
+	}
+}
+
+// WithLogLevel sets the level of the default log handler, defaults to the AWS_LAMBDA_LOG_LEVEL environment variable or slog.LevelInfo
+func WithLogLevel(level slog.Leveler) Option {
+	return func(cfg *config) {
+		cfg.logLevel = level
+	}
+}
+
+// WithHooks adds hooks to the runtime, hooks are called in the order they were added
+func WithHooks(hooks Hooks) Option {
+	return func(cfg *config) {
+		cfg.hooks = append(cfg.hooks, hooks)
+	}
+}
+
+// WithDeadlineMargin sets how long before the invocation deadline the handler context is cancelled, defaults to DefaultDeadlineMargin
+func WithDeadlineMargin(margin time.Duration) Option {
+	return func(cfg *config) {
+		cfg.deadlineMargin = margin
+	}
+}
+
+// WithCrashOnPanic stops the runtime when a handler panics instead of reporting a Runtime.HandlerPanic error and serving the next invocation
+func WithCrashOnPanic() Option {
+	return func(cfg *config) {
+		cfg.crashOnPanic = true
+	}
+}
+
+// WithResponseOverflow sets the handler called with responses larger than MaxLambdaInvokeSize, by default they are reported as a ResponseSizeError
+func WithResponseOverflow(overflow ResponseOverflowHandler) Option {
+	return func(cfg *config) {
+		cfg.overflow = overflow
+	}
+}
+
+// OnShutdown adds a hook run when the runtime receives SIGTERM or SIGINT, hooks run in the order they were added and share a context bounded by the shutdown timeout
+func OnShutdown(hook func(ctx context.Context)) Option {
+	return func(cfg *config) {
+		cfg.shutdownHooks = append(cfg.shutdownHooks, hook)
+	}
+}
+
+// WithShutdownTimeout sets how long shutdown hooks may run, defaults to DefaultShutdownTimeout
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(cfg *config) {
+		cfg.shutdownTimeout = timeout
+	}
+}
+
+// WithoutTraceEnv stops the runtime from setting the _X_AMZN_TRACE_ID environment variable for every invocation, the trace stays available in RequestMeta
+func WithoutTraceEnv() Option {
+	return func(cfg *config) {
+		cfg.traceEnvOff = true
+	}
+}
+
+// WithRetryPolicy sets how failed Runtime API calls are retried, defaults to DefaultRetryPolicy; a policy with MaxAttempts of 1 disables retries
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(cfg *config) {
+		cfg.retry = policy
+	}
+}
+
+// WithInvocationLimit stops the runtime after it has served n invocations, the shutdown hooks run and StartWithOptions returns; 0 serves invocations until the process is stopped
+func WithInvocationLimit(n int) Option {
+	return func(cfg *config) {
+		cfg.maxInvocations = n
+	}
+}
@@ -0,0 +1,168 @@
+package llb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_newConfig(t *testing.T) {
+	t.Setenv(envRuntimeDomain, "domain")
+	t.Setenv(envDeadlineMargin, "")
+
+	cfg := newConfig(nil)
+	if cfg.client != http.DefaultClient {
+		t.Error("newConfig() did not default to http.DefaultClient")
+	}
+	if cfg.endpoint != "domain" {
+		t.Errorf("newConfig() endpoint = %s, want domain", cfg.endpoint)
+	}
+	if cfg.logger == nil {
+		t.Error("newConfig() did not set a default logger")
+	}
+	if cfg.deadlineMargin != DefaultDeadlineMargin {
+		t.Errorf("newConfig() deadlineMargin = %v, want %v", cfg.deadlineMargin, DefaultDeadlineMargin)
+	}
+	if cfg.shutdownTimeout != DefaultShutdownTimeout {
+		t.Errorf("newConfig() shutdownTimeout = %v, want %v", cfg.shutdownTimeout, DefaultShutdownTimeout)
+	}
+}
+
+func Test_newConfig_options(t *testing.T) {
+	client := &http.Client{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	fatalCalled := false
+
+	cfg := newConfig([]Option{
+		WithHTTPClient(client),
+		WithRuntimeAPI("localhost:9001"),
+		WithFatal(func(error) { fatalCalled = true }),
+		WithLogger(logger),
+		WithHooks(Hooks{}),
+		WithHooks(Hooks{}),
+		WithDeadlineMargin(time.Second),
+		WithCrashOnPanic(),
+		WithResponseOverflow(func(ctx context.Context, r io.Reader) (io.Reader, error) { return r, nil }),
+		OnShutdown(func(ctx context.Context) {}),
+		WithShutdownTimeout(time.Minute),
+		WithoutTraceEnv(),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 5}),
+		WithInvocationLimit(2),
+	})
+
+	if cfg.client != client {
+		t.Error("WithHTTPClient was not applied")
+	}
+	if cfg.endpoint != "localhost:9001" {
+		t.Errorf("WithRuntimeAPI endpoint = %s", cfg.endpoint)
+	}
+	if cfg.fatal(nil); !fatalCalled {
+		t.Error("WithFatal was not applied")
+	}
+	if cfg.logger != logger {
+		t.Error("WithLogger was not applied")
+	}
+	if len(cfg.hooks) != 2 {
+		t.Errorf("WithHooks added %d hooks, want 2", len(cfg.hooks))
+	}
+	if cfg.deadlineMargin != time.Second {
+		t.Errorf("WithDeadlineMargin = %v", cfg.deadlineMargin)
+	}
+	if !cfg.crashOnPanic {
+		t.Error("WithCrashOnPanic was not applied")
+	}
+	if cfg.overflow == nil {
+		t.Error("WithResponseOverflow was not applied")
+	}
+	if cfg.retry.MaxAttempts != 5 {
+		t.Errorf("WithRetryPolicy MaxAttempts = %d", cfg.retry.MaxAttempts)
+	}
+	if cfg.maxInvocations != 2 {
+		t.Errorf("WithInvocationLimit = %d", cfg.maxInvocations)
+	}
+	if len(cfg.shutdownHooks) != 1 {
+		t.Errorf("OnShutdown added %d hooks, want 1", len(cfg.shutdownHooks))
+	}
+	if cfg.shutdownTimeout != time.Minute {
+		t.Errorf("WithShutdownTimeout = %v", cfg.shutdownTimeout)
+	}
+	if !cfg.traceEnvOff {
+		t.Error("WithoutTraceEnv was not applied")
+	}
+
+	transport := &http.Transport{}
+	cfg = newConfig([]Option{WithTransport(transport)})
+	if client, ok := cfg.client.(*http.Client); !ok || client.Transport != transport {
+		t.Error("WithTransport was not applied")
+	}
+}
+
+type ctxKey struct{}
+
+func Test_runtime_next_hooks(t *testing.T) {
+	calls := []string{}
+	rt := newRuntime(
+		func(ctx context.Context, r io.Reader) (io.Reader, error) {
+			calls = append(calls, "handler:"+ctx.Value(ctxKey{}).(string))
+			return bytes.NewBufferString(""), errors.New("error")
+		},
+		mockAPI{
+			_getRuntimeInvocationNext: func() (*http.Response, error) {
+				calls = append(calls, "next")
+				return newValidNextResponse(), nil
+			},
+			_postRuntimeInvocationError: func(requestId string, err error) (*http.Response, error) {
+				calls = append(calls, "error")
+				return nil, err
+			},
+		},
+		nil,
+	)
+	rt.hooks = []Hooks{
+		{
+			BeforeNext: func() { calls = append(calls, "beforeNext") },
+			BeforeInvoke: func(ctx context.Context) context.Context {
+				calls = append(calls, "beforeInvoke")
+				return context.WithValue(ctx, ctxKey{}, "value")
+			},
+		},
+		{
+			AfterInvoke: func(ctx context.Context, err error) {
+				calls = append(calls, "afterInvoke:"+ctx.Value(ctxKey{}).(string)+":"+err.Error())
+			},
+		},
+	}
+
+	if err := rt.next(); err == nil {
+		t.Fatal("runtime.next() did not return the handler error")
+	}
+
+	want := []string{"beforeNext", "next", "beforeInvoke", "handler:value", "error", "afterInvoke:value:error"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("hook calls = %v, want %v", calls, want)
+	}
+}
+
+func Test_newConfig_logHandler(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+
+	cfg := newConfig([]Option{WithLogHandler(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelWarn}))})
+	cfg.logger.Info("dropped")
+	cfg.logger.Warn("kept")
+
+	if out := buf.String(); strings.Contains(out, "dropped") || !strings.Contains(out, "kept") {
+		t.Errorf("WithLogHandler logged %q", out)
+	}
+
+	cfg = newConfig([]Option{WithLogLevel(slog.LevelError)})
+	if cfg.logger.Enabled(context.Background(), slog.LevelWarn) {
+		t.Error("WithLogLevel was not applied")
+	}
+}
@@ -0,0 +1,316 @@
+package eventfixtures
+
+import (
+	"encoding/base64"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type (
+	// request holds what the API Gateway v1 and v2 builders have in common
+	request struct {
+		method         string
+		path           string
+		body           string
+		base64         bool
+		headers        map[string][]string
+		query          url.Values
+		pathParameters map[string]string
+		stageVariables map[string]string
+	}
+
+	// APIGatewayBuilder builds an events.APIGatewayProxyRequest for a REST API proxy integration
+	APIGatewayBuilder struct {
+		request
+		resource string
+	}
+
+	// APIGatewayV2Builder builds an events.APIGatewayV2HTTPRequest for an HTTP API with payload format 2.0
+	APIGatewayV2Builder struct {
+		request
+		routeKey string
+		cookies  []string
+	}
+)
+
+const (
+	defaultApiId     = "1234567890"
+	defaultSourceIp  = "203.0.113.10"
+	defaultUserAgent = "curl/8.4.0"
+	defaultStage     = "prod"
+	httpProtocol     = "HTTP/1.1"
+	requestTimeForm  = "02/Jan/2006:15:04:05 -0700"
+)
+
+func newRequest() request {
+	return request{
+		method: "GET",
+		path:   "/",
+		headers: map[string][]string{
+			"accept":     {"*/*"},
+			"host":       {defaultApiId + ".execute-api." + DefaultRegion + ".amazonaws.com"},
+			"user-agent": {defaultUserAgent},
+		},
+		query:          url.Values{},
+		pathParameters: map[string]string{},
+		stageVariables: map[string]string{},
+	}
+}
+
+func (r *request) withHeader(key, value string) {
+	key = strings.ToLower(key)
+	r.headers[key] = append(r.headers[key], value)
+}
+
+func (r *request) withBody(body string, base64Encoded bool) {
+	r.body, r.base64 = body, base64Encoded
+	if base64Encoded {
+		r.body = base64.StdEncoding.EncodeToString([]byte(body))
+	}
+}
+
+// singleHeaders joins repeated headers with commas like API Gateway does for the single value maps
+func (r *request) singleHeaders() map[string]string {
+	headers := make(map[string]string, len(r.headers))
+	for key, values := range r.headers {
+		headers[key] = strings.Join(values, ",")
+	}
+
+	return headers
+}
+
+func (r *request) multiHeaders() map[string][]string {
+	headers := make(map[string][]string, len(r.headers))
+	for key, values := range r.headers {
+		headers[key] = append([]string{}, values...)
+	}
+
+	return headers
+}
+
+// mapOrNil returns nil for an empty map, API Gateway sends null for empty parameter maps
+func mapOrNil[V any](m map[string]V) map[string]V {
+	if len(m) == 0 {
+		return nil
+	}
+
+	return m
+}
+
+// APIGateway returns a builder for a GET / request to a REST API
+func APIGateway() *APIGatewayBuilder {
+	return &APIGatewayBuilder{request: newRequest(), resource: "/"}
+}
+
+// WithMethod sets the HTTP method
+func (b *APIGatewayBuilder) WithMethod(method string) *APIGatewayBuilder {
+	b.method = strings.ToUpper(method)
+	return b
+}
+
+// WithPath sets the request path, the resource defaults to the same path
+func (b *APIGatewayBuilder) WithPath(path string) *APIGatewayBuilder {
+	b.path, b.resource = path, path
+	return b
+}
+
+// WithResource sets the resource path defined in API Gateway, e.g. /users/{id}
+func (b *APIGatewayBuilder) WithResource(resource string) *APIGatewayBuilder {
+	b.resource = resource
+	return b
+}
+
+// WithHeader adds a header, header names are lower cased
+func (b *APIGatewayBuilder) WithHeader(key, value string) *APIGatewayBuilder {
+	b.withHeader(key, value)
+	return b
+}
+
+// WithQuery adds a query string parameter
+func (b *APIGatewayBuilder) WithQuery(key, value string) *APIGatewayBuilder {
+	b.query.Add(key, value)
+	return b
+}
+
+// WithPathParameter sets a path parameter of the resource
+func (b *APIGatewayBuilder) WithPathParameter(key, value string) *APIGatewayBuilder {
+	b.pathParameters[key] = value
+	return b
+}
+
+// WithStageVariable sets a stage variable
+func (b *APIGatewayBuilder) WithStageVariable(key, value string) *APIGatewayBuilder {
+	b.stageVariables[key] = value
+	return b
+}
+
+// WithBody sets the request body, it is base64 encoded in the event if base64Encoded is set
+func (b *APIGatewayBuilder) WithBody(body string, base64Encoded bool) *APIGatewayBuilder {
+	b.withBody(body, base64Encoded)
+	return b
+}
+
+// Build returns the request, the builder may be used again afterwards
+func (b *APIGatewayBuilder) Build() events.APIGatewayProxyRequest {
+	query, multiQuery := map[string]string{}, map[string][]string{}
+	for key, values := range b.query {
+		query[key] = values[len(values)-1]
+		multiQuery[key] = append([]string{}, values...)
+	}
+
+	return events.APIGatewayProxyRequest{
+		Resource:                        b.resource,
+		Path:                            b.path,
+		HTTPMethod:                      b.method,
+		Headers:                         b.singleHeaders(),
+		MultiValueHeaders:               b.multiHeaders(),
+		QueryStringParameters:           mapOrNil(query),
+		MultiValueQueryStringParameters: mapOrNil(multiQuery),
+		PathParameters:                  mapOrNil(copyMap(b.pathParameters)),
+		StageVariables:                  mapOrNil(copyMap(b.stageVariables)),
+		Body:                            b.body,
+		IsBase64Encoded:                 b.base64,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			AccountID:         DefaultAccountId,
+			ResourceID:        "abcdef",
+			Stage:             defaultStage,
+			DomainName:        defaultApiId + ".execute-api." + DefaultRegion + ".amazonaws.com",
+			DomainPrefix:      defaultApiId,
+			RequestID:         fixtureId("apigateway", 0),
+			ExtendedRequestID: "EXAMPLE=",
+			Protocol:          httpProtocol,
+			Identity: events.APIGatewayRequestIdentity{
+				SourceIP:  defaultSourceIp,
+				UserAgent: defaultUserAgent,
+			},
+			ResourcePath:     b.resource,
+			Path:             "/" + defaultStage + b.path,
+			HTTPMethod:       b.method,
+			RequestTime:      DefaultTime.Format(requestTimeForm),
+			RequestTimeEpoch: DefaultTime.UnixMilli(),
+			APIID:            defaultApiId,
+		},
+	}
+}
+
+// APIGatewayV2 returns a builder for a GET / request to an HTTP API using the $default route
+func APIGatewayV2() *APIGatewayV2Builder {
+	return &APIGatewayV2Builder{request: newRequest(), routeKey: "$default"}
+}
+
+// WithMethod sets the HTTP method
+func (b *APIGatewayV2Builder) WithMethod(method string) *APIGatewayV2Builder {
+	b.method = strings.ToUpper(method)
+	return b
+}
+
+// WithPath sets the raw request path
+func (b *APIGatewayV2Builder) WithPath(path string) *APIGatewayV2Builder {
+	b.path = path
+	return b
+}
+
+// WithRouteKey sets the route that matched the request, e.g. "GET /users/{id}"
+func (b *APIGatewayV2Builder) WithRouteKey(routeKey string) *APIGatewayV2Builder {
+	b.routeKey = routeKey
+	return b
+}
+
+// WithHeader adds a header, header names are lower cased
+func (b *APIGatewayV2Builder) WithHeader(key, value string) *APIGatewayV2Builder {
+	b.withHeader(key, value)
+	return b
+}
+
+// WithCookie adds a cookie in name=value form
+func (b *APIGatewayV2Builder) WithCookie(cookie string) *APIGatewayV2Builder {
+	b.cookies = append(b.cookies, cookie)
+	return b
+}
+
+// WithQuery adds a query string parameter
+func (b *APIGatewayV2Builder) WithQuery(key, value string) *APIGatewayV2Builder {
+	b.query.Add(key, value)
+	return b
+}
+
+// WithPathParameter sets a path parameter of the route
+func (b *APIGatewayV2Builder) WithPathParameter(key, value string) *APIGatewayV2Builder {
+	b.pathParameters[key] = value
+	return b
+}
+
+// WithStageVariable sets a stage variable
+func (b *APIGatewayV2Builder) WithStageVariable(key, value string) *APIGatewayV2Builder {
+	b.stageVariables[key] = value
+	return b
+}
+
+// WithBody sets the request body, it is base64 encoded in the event if base64Encoded is set
+func (b *APIGatewayV2Builder) WithBody(body string, base64Encoded bool) *APIGatewayV2Builder {
+	b.withBody(body, base64Encoded)
+	return b
+}
+
+// Build returns the request, the builder may be used again afterwards
+func (b *APIGatewayV2Builder) Build() events.APIGatewayV2HTTPRequest {
+	// payload format 2.0 joins repeated query parameters with commas
+	query := map[string]string{}
+	keys := make([]string, 0, len(b.query))
+	for key, values := range b.query {
+		query[key] = strings.Join(values, ",")
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var raw []string
+	for _, key := range keys {
+		for _, value := range b.query[key] {
+			raw = append(raw, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+
+	return events.APIGatewayV2HTTPRequest{
+		Version:               "2.0",
+		RouteKey:              b.routeKey,
+		RawPath:               b.path,
+		RawQueryString:        strings.Join(raw, "&"),
+		Cookies:               append([]string(nil), b.cookies...),
+		Headers:               b.singleHeaders(),
+		QueryStringParameters: mapOrNil(query),
+		PathParameters:        mapOrNil(copyMap(b.pathParameters)),
+		StageVariables:        mapOrNil(copyMap(b.stageVariables)),
+		Body:                  b.body,
+		IsBase64Encoded:       b.base64,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RouteKey:     b.routeKey,
+			AccountID:    DefaultAccountId,
+			Stage:        "$default",
+			RequestID:    "EXAMPLE=",
+			APIID:        defaultApiId,
+			DomainName:   defaultApiId + ".execute-api." + DefaultRegion + ".amazonaws.com",
+			DomainPrefix: defaultApiId,
+			Time:         DefaultTime.Format(requestTimeForm),
+			TimeEpoch:    DefaultTime.UnixMilli(),
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:    b.method,
+				Path:      b.path,
+				Protocol:  httpProtocol,
+				SourceIP:  defaultSourceIp,
+				UserAgent: defaultUserAgent,
+			},
+		},
+	}
+}
+
+func copyMap[K comparable, V any](m map[K]V) map[K]V {
+	c := make(map[K]V, len(m))
+	for key, value := range m {
+		c[key] = value
+	}
+
+	return c
+}
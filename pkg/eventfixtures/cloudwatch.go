@@ -0,0 +1,76 @@
+package eventfixtures
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type (
+	// CloudWatchEventBuilder builds an events.CloudWatchEvent, it defaults to an EventBridge scheduled event
+	CloudWatchEventBuilder struct {
+		event events.CloudWatchEvent
+	}
+)
+
+const (
+	defaultCloudWatchSource     = "aws.events"
+	defaultCloudWatchDetailType = "Scheduled Event"
+	defaultCloudWatchRule       = "my-schedule"
+)
+
+// CloudWatchEvent returns a builder for a scheduled event in DefaultRegion
+func CloudWatchEvent() *CloudWatchEventBuilder {
+	return &CloudWatchEventBuilder{
+		event: events.CloudWatchEvent{
+			Version:    "0",
+			ID:         fixtureId("cloudwatch-event", 0),
+			DetailType: defaultCloudWatchDetailType,
+			Source:     defaultCloudWatchSource,
+			AccountID:  DefaultAccountId,
+			Time:       DefaultTime,
+			Region:     DefaultRegion,
+			Resources:  []string{"arn:aws:events:" + DefaultRegion + ":" + DefaultAccountId + ":rule/" + defaultCloudWatchRule},
+			Detail:     json.RawMessage(`{}`),
+		},
+	}
+}
+
+// WithSource sets the source of the event, e.g. aws.s3 or a custom application source
+func (b *CloudWatchEventBuilder) WithSource(source string) *CloudWatchEventBuilder {
+	b.event.Source = source
+	return b
+}
+
+// WithDetailType sets the detail-type of the event
+func (b *CloudWatchEventBuilder) WithDetailType(detailType string) *CloudWatchEventBuilder {
+	b.event.DetailType = detailType
+	return b
+}
+
+// WithDetail sets the detail to the JSON encoding of detail, it panics if detail can not be marshalled
+func (b *CloudWatchEventBuilder) WithDetail(detail any) *CloudWatchEventBuilder {
+	b.event.Detail = JSON(detail)
+	return b
+}
+
+// WithResources replaces the resources of the event
+func (b *CloudWatchEventBuilder) WithResources(resources ...string) *CloudWatchEventBuilder {
+	b.event.Resources = append([]string{}, resources...)
+	return b
+}
+
+// WithTime sets the time of the event
+func (b *CloudWatchEventBuilder) WithTime(t time.Time) *CloudWatchEventBuilder {
+	b.event.Time = t
+	return b
+}
+
+// Build returns the event, the builder may be used again afterwards
+func (b *CloudWatchEventBuilder) Build() events.CloudWatchEvent {
+	event := b.event
+	event.Resources = append([]string{}, b.event.Resources...)
+
+	return event
+}
@@ -0,0 +1,44 @@
+package eventfixtures
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultRegion    = "us-east-1"
+	DefaultAccountId = "123456789012"
+)
+
+var (
+	// DefaultTime is the time of every event unless a builder sets another, it keeps generated fixtures stable
+	DefaultTime = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+)
+
+// fixtureId returns a UUID formatted id that is the same for every build of kind and n
+func fixtureId(kind string, n int) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("llb/eventfixtures/%s/%d", kind, n)))
+	sum[6] = sum[6]&0x0f | 0x40
+	sum[8] = sum[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// JSON marshals an event built by this package, it panics if the event can not be marshalled which only happens for invalid custom details
+func JSON(event any) []byte {
+	data, err := json.Marshal(event)
+	if err != nil {
+		panic(fmt.Errorf("%w; eventfixtures.JSON", err))
+	}
+
+	return data
+}
@@ -0,0 +1,202 @@
+package eventfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/RileyMcCuen/llb"
+	"github.com/RileyMcCuen/llb/pkg/handlerutil"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestSQS(t *testing.T) {
+	event := SQS().WithMessage("first").WithMessage("second").WithMessageAttribute("tenant", "a").Build()
+
+	if len(event.Records) != 2 {
+		t.Fatalf("SQS() built %d records, want 2", len(event.Records))
+	}
+	first, second := event.Records[0], event.Records[1]
+	if first.Body != "first" || first.Md5OfBody != md5Hex("first") || first.EventSource != "aws:sqs" || first.AWSRegion != DefaultRegion {
+		t.Errorf("first record = %+v", first)
+	}
+	if first.MessageId == second.MessageId {
+		t.Error("SQS() records share a message id")
+	}
+	if got := second.MessageAttributes["tenant"]; got.StringValue == nil || *got.StringValue != "a" || second.Md5OfMessageAttributes == "" {
+		t.Errorf("second record attributes = %+v", second.MessageAttributes)
+	}
+
+	if got := SQS().Build(); len(got.Records) != 1 || got.Records[0].Body != DefaultSQSBody {
+		t.Errorf("SQS().Build() without messages = %+v", got)
+	}
+}
+
+func TestSNS(t *testing.T) {
+	event := SNS().WithTopicArn("arn:aws:sns:eu-west-1:123456789012:topic").WithMessage("hello").WithSubject("subject").WithMessageAttribute("key", "value").Build()
+
+	if len(event.Records) != 1 {
+		t.Fatalf("SNS() built %d records, want 1", len(event.Records))
+	}
+	record := event.Records[0]
+	if record.SNS.Message != "hello" || record.SNS.Subject != "subject" || record.SNS.TopicArn != "arn:aws:sns:eu-west-1:123456789012:topic" {
+		t.Errorf("record = %+v", record)
+	}
+	if want := map[string]interface{}{"Type": "String", "Value": "value"}; !reflect.DeepEqual(record.SNS.MessageAttributes["key"], want) {
+		t.Errorf("record attributes = %+v", record.SNS.MessageAttributes)
+	}
+}
+
+func TestSQSBuilder_Build_reuse(t *testing.T) {
+	builder := SQS()
+	builder.Build()
+
+	first := builder.WithMessage("first").WithMessageAttribute("tenant", "a").Build()
+	if len(first.Records) != 1 || first.Records[0].Body != "first" {
+		t.Fatalf("Build() after an empty Build() = %+v, want only the first message", first.Records)
+	}
+
+	builder.WithMessageAttribute("tenant", "b").WithMessageAttribute("region", "c")
+	if got := first.Records[0].MessageAttributes; len(got) != 1 || *got["tenant"].StringValue != "a" {
+		t.Errorf("built record attributes changed with the builder: %+v", got)
+	}
+}
+
+func TestSNSBuilder_Build_reuse(t *testing.T) {
+	builder := SNS()
+	builder.Build()
+
+	first := builder.WithMessage("first").WithMessageAttribute("key", "a").Build()
+	if len(first.Records) != 1 || first.Records[0].SNS.Message != "first" {
+		t.Fatalf("Build() after an empty Build() = %+v, want only the first message", first.Records)
+	}
+
+	builder.WithMessageAttribute("key", "b").WithMessageAttribute("other", "c")
+	if got := first.Records[0].SNS.MessageAttributes; len(got) != 1 || got["key"].(map[string]interface{})["Value"] != "a" {
+		t.Errorf("built record attributes changed with the builder: %+v", got)
+	}
+}
+
+func TestCloudWatchEvent(t *testing.T) {
+	event := CloudWatchEvent().WithSource("app.orders").WithDetailType("Order Placed").WithDetail(map[string]int{"id": 1}).WithResources().Build()
+
+	if event.Source != "app.orders" || event.DetailType != "Order Placed" || string(event.Detail) != `{"id":1}` || len(event.Resources) != 0 {
+		t.Errorf("CloudWatchEvent() = %+v", event)
+	}
+	if got := CloudWatchEvent().Build(); got.DetailType != "Scheduled Event" || got.Source != "aws.events" {
+		t.Errorf("CloudWatchEvent() default = %+v", got)
+	}
+}
+
+func TestAPIGateway(t *testing.T) {
+	event := APIGateway().
+		WithMethod("post").
+		WithPath("/users/1").
+		WithResource("/users/{id}").
+		WithPathParameter("id", "1").
+		WithQuery("tag", "a").
+		WithQuery("tag", "b").
+		WithHeader("X-Custom", "one").
+		WithHeader("x-custom", "two").
+		WithBody("payload", true).
+		Build()
+
+	if event.HTTPMethod != "POST" || event.Path != "/users/1" || event.Resource != "/users/{id}" || event.PathParameters["id"] != "1" {
+		t.Errorf("APIGateway() = %+v", event)
+	}
+	if event.QueryStringParameters["tag"] != "b" || !reflect.DeepEqual(event.MultiValueQueryStringParameters["tag"], []string{"a", "b"}) {
+		t.Errorf("APIGateway() query = %v, %v", event.QueryStringParameters, event.MultiValueQueryStringParameters)
+	}
+	if event.Headers["x-custom"] != "one,two" || len(event.MultiValueHeaders["x-custom"]) != 2 {
+		t.Errorf("APIGateway() headers = %v, %v", event.Headers, event.MultiValueHeaders)
+	}
+	if body, _ := base64.StdEncoding.DecodeString(event.Body); !event.IsBase64Encoded || string(body) != "payload" {
+		t.Errorf("APIGateway() body = %q", event.Body)
+	}
+	if event.RequestContext.HTTPMethod != "POST" || event.RequestContext.ResourcePath != "/users/{id}" {
+		t.Errorf("APIGateway() request context = %+v", event.RequestContext)
+	}
+}
+
+func TestAPIGatewayV2(t *testing.T) {
+	event := APIGatewayV2().
+		WithMethod("put").
+		WithPath("/items").
+		WithRouteKey("PUT /items").
+		WithQuery("b", "2").
+		WithQuery("a", "1 2").
+		WithQuery("a", "3").
+		WithCookie("session=abc").
+		WithBody(`{"id":1}`, false).
+		Build()
+
+	if event.RawPath != "/items" || event.RouteKey != "PUT /items" || event.RequestContext.HTTP.Method != "PUT" || event.Body != `{"id":1}` {
+		t.Errorf("APIGatewayV2() = %+v", event)
+	}
+	if event.RawQueryString != "a=1+2&a=3&b=2" || event.QueryStringParameters["a"] != "1 2,3" {
+		t.Errorf("APIGatewayV2() query = %q, %v", event.RawQueryString, event.QueryStringParameters)
+	}
+	if !reflect.DeepEqual(event.Cookies, []string{"session=abc"}) {
+		t.Errorf("APIGatewayV2() cookies = %v", event.Cookies)
+	}
+}
+
+// TestHandlers decodes every fixture with the handlerutil handler of its type
+func TestHandlers(t *testing.T) {
+	var sqs events.SQSEvent
+	var sns events.SNSEvent
+	var cwe events.CloudWatchEvent
+	var apigw events.APIGatewayProxyRequest
+	var apigwV2 events.APIGatewayV2HTTPRequest
+
+	tests := []struct {
+		name    string
+		event   any
+		handler func(ctx context.Context, data []byte) error
+		got     any
+	}{
+		{"SQS", SQS().Build(), invoke(handlerutil.SQSHandler(func(ctx context.Context, in events.SQSEvent) error { sqs = in; return nil }, nil)), &sqs},
+		{"SNS", SNS().Build(), invoke(handlerutil.SNSHandler(func(ctx context.Context, in events.SNSEvent) error { sns = in; return nil }, nil)), &sns},
+		{"CloudWatchEvent", CloudWatchEvent().Build(), invoke(handlerutil.CWEHandler(func(ctx context.Context, in events.CloudWatchEvent) error { cwe = in; return nil }, nil)), &cwe},
+		{"APIGateway", APIGateway().Build(), invoke(handlerutil.APIGatewayHandler(func(ctx context.Context, in events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			apigw = in
+			return events.APIGatewayProxyResponse{}, nil
+		}, nil)), &apigw},
+		{"APIGatewayV2", APIGatewayV2().Build(), invoke(handlerutil.APIGatewayV2Handler(func(ctx context.Context, in events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+			apigwV2 = in
+			return events.APIGatewayV2HTTPResponse{}, nil
+		}, nil)), &apigwV2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.handler(context.Background(), JSON(tt.event)); err != nil {
+				t.Fatalf("handler error = %v", err)
+			}
+
+			got, _ := json.Marshal(reflect.ValueOf(tt.got).Elem().Interface())
+			if !bytes.Equal(got, JSON(tt.event)) {
+				t.Errorf("handler decoded %s, want %s", got, JSON(tt.event))
+			}
+		})
+	}
+}
+
+func invoke(handler llb.Handler) func(ctx context.Context, data []byte) error {
+	return func(ctx context.Context, data []byte) error {
+		_, err := handler(ctx, bytes.NewReader(data))
+		return err
+	}
+}
+
+func Test_fixtureId(t *testing.T) {
+	if fixtureId("sqs", 0) != fixtureId("sqs", 0) || fixtureId("sqs", 0) == fixtureId("sqs", 1) {
+		t.Error("fixtureId() is not stable per kind and index")
+	}
+	if id := fixtureId("sqs", 0); len(id) != 36 || id[14] != '4' {
+		t.Errorf("fixtureId() = %s, want a version 4 UUID", id)
+	}
+}
@@ -0,0 +1,96 @@
+package eventfixtures
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type (
+	// SNSBuilder builds an events.SNSEvent, an event without notifications gets one default notification
+	SNSBuilder struct {
+		topicArn string
+		records  []events.SNSEventRecord
+	}
+)
+
+const (
+	DefaultSNSMessage = "Hello from SNS!"
+	defaultSNSTopic   = "my-topic"
+	snsEventSource    = "aws:sns"
+)
+
+// SNS returns a builder for notifications from a topic in DefaultRegion
+func SNS() *SNSBuilder {
+	return &SNSBuilder{
+		topicArn: "arn:aws:sns:" + DefaultRegion + ":" + DefaultAccountId + ":" + defaultSNSTopic,
+	}
+}
+
+// WithTopicArn sets the topic the notifications come from
+func (b *SNSBuilder) WithTopicArn(arn string) *SNSBuilder {
+	b.topicArn = arn
+	return b
+}
+
+// WithMessage adds a notification with message
+func (b *SNSBuilder) WithMessage(message string) *SNSBuilder {
+	b.records = append(b.records, newSNSRecord(message, len(b.records)))
+	return b
+}
+
+func newSNSRecord(message string, n int) events.SNSEventRecord {
+	return events.SNSEventRecord{
+		EventVersion: "1.0",
+		EventSource:  snsEventSource,
+		SNS: events.SNSEntity{
+			Signature:         "EXAMPLE",
+			MessageID:         fixtureId("sns", n),
+			Type:              "Notification",
+			MessageAttributes: map[string]interface{}{},
+			SignatureVersion:  "1",
+			Timestamp:         DefaultTime,
+			SigningCertURL:    "https://sns." + DefaultRegion + ".amazonaws.com/SimpleNotificationService-EXAMPLE.pem",
+			Message:           message,
+		},
+	}
+}
+
+// WithSubject sets the subject of the last notification, a default notification is added first if there is none
+func (b *SNSBuilder) WithSubject(subject string) *SNSBuilder {
+	b.last().SNS.Subject = subject
+	return b
+}
+
+// WithMessageAttribute adds a string message attribute to the last notification, a default notification is added first if there is none
+func (b *SNSBuilder) WithMessageAttribute(name, value string) *SNSBuilder {
+	b.last().SNS.MessageAttributes[name] = map[string]interface{}{"Type": "String", "Value": value}
+	return b
+}
+
+func (b *SNSBuilder) last() *events.SNSEventRecord {
+	if len(b.records) == 0 {
+		b.WithMessage(DefaultSNSMessage)
+	}
+
+	return &b.records[len(b.records)-1]
+}
+
+// Build returns the event, the builder may be used again afterwards and later changes do not affect the event
+func (b *SNSBuilder) Build() events.SNSEvent {
+	source := b.records
+	if len(source) == 0 {
+		source = []events.SNSEventRecord{newSNSRecord(DefaultSNSMessage, 0)}
+	}
+
+	records := make([]events.SNSEventRecord, len(source))
+	for i, record := range source {
+		record.SNS.MessageAttributes = copyMap(record.SNS.MessageAttributes)
+		subscription := fixtureId("sns-subscription", i)
+
+		record.EventSubscriptionArn = b.topicArn + ":" + subscription
+		record.SNS.TopicArn = b.topicArn
+		record.SNS.UnsubscribeURL = "https://sns." + DefaultRegion + ".amazonaws.com/?Action=Unsubscribe&SubscriptionArn=" + record.EventSubscriptionArn
+		records[i] = record
+	}
+
+	return events.SNSEvent{Records: records}
+}
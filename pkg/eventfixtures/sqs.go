@@ -0,0 +1,106 @@
+package eventfixtures
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type (
+	// SQSBuilder builds an events.SQSEvent, a batch without messages gets one default message
+	SQSBuilder struct {
+		queueArn string
+		region   string
+		messages []events.SQSMessage
+	}
+)
+
+const (
+	DefaultSQSBody  = "Hello from SQS!"
+	defaultSQSQueue = "my-queue"
+	sqsEventSource  = "aws:sqs"
+)
+
+// SQS returns a builder for an SQS batch from a queue in DefaultRegion
+func SQS() *SQSBuilder {
+	return &SQSBuilder{
+		queueArn: "arn:aws:sqs:" + DefaultRegion + ":" + DefaultAccountId + ":" + defaultSQSQueue,
+		region:   DefaultRegion,
+	}
+}
+
+// WithQueueArn sets the queue the messages come from
+func (b *SQSBuilder) WithQueueArn(arn string) *SQSBuilder {
+	b.queueArn = arn
+	return b
+}
+
+// WithRegion sets the region of the messages
+func (b *SQSBuilder) WithRegion(region string) *SQSBuilder {
+	b.region = region
+	return b
+}
+
+// WithMessage adds a message with body to the batch
+func (b *SQSBuilder) WithMessage(body string) *SQSBuilder {
+	b.messages = append(b.messages, newSQSMessage(body, len(b.messages)))
+	return b
+}
+
+func newSQSMessage(body string, n int) events.SQSMessage {
+	sent := strconv.FormatInt(DefaultTime.UnixMilli(), 10)
+
+	return events.SQSMessage{
+		MessageId:     fixtureId("sqs", n),
+		ReceiptHandle: "AQEB" + md5Hex(fixtureId("sqs-receipt", n)),
+		Body:          body,
+		Md5OfBody:     md5Hex(body),
+		Attributes: map[string]string{
+			"ApproximateReceiveCount":          "1",
+			"SentTimestamp":                    sent,
+			"SenderId":                         "AIDAIENQZJOLO23YVJ4VO",
+			"ApproximateFirstReceiveTimestamp": sent,
+		},
+		MessageAttributes: map[string]events.SQSMessageAttribute{},
+	}
+}
+
+// WithMessageAttribute adds a string message attribute to the last message, a default message is added first if there is none
+func (b *SQSBuilder) WithMessageAttribute(name, value string) *SQSBuilder {
+	if len(b.messages) == 0 {
+		b.WithMessage(DefaultSQSBody)
+	}
+
+	b.messages[len(b.messages)-1].MessageAttributes[name] = events.SQSMessageAttribute{
+		StringValue:      &value,
+		StringListValues: []string{},
+		BinaryListValues: [][]byte{},
+		DataType:         "String",
+	}
+
+	return b
+}
+
+// Build returns the batch, the builder may be used again afterwards and later changes do not affect the batch
+func (b *SQSBuilder) Build() events.SQSEvent {
+	messages := b.messages
+	if len(messages) == 0 {
+		messages = []events.SQSMessage{newSQSMessage(DefaultSQSBody, 0)}
+	}
+
+	records := make([]events.SQSMessage, len(messages))
+	for i, message := range messages {
+		message.Attributes = copyMap(message.Attributes)
+		message.MessageAttributes = copyMap(message.MessageAttributes)
+		message.EventSource = sqsEventSource
+		message.EventSourceARN = b.queueArn
+		message.AWSRegion = b.region
+		// a stand in for the SQS attribute digest, it only has to change with the attributes
+		if len(message.MessageAttributes) > 0 {
+			message.Md5OfMessageAttributes = md5Hex(string(JSON(message.MessageAttributes)))
+		}
+		records[i] = message
+	}
+
+	return events.SQSEvent{Records: records}
+}
@@ -0,0 +1,177 @@
+package extension
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/RileyMcCuen/llb"
+)
+
+type (
+	httpClient interface {
+		Do(*http.Request) (*http.Response, error)
+	}
+	defaultAPI struct {
+		domain       string
+		registerUrl  string
+		nextUrl      string
+		initErrorUrl string
+		exitErrorUrl string
+		client       httpClient
+	}
+	api interface {
+		register(name string, events []EventType) (id string, resp RegisterResponse, err error)
+		next(id string) (Event, error)
+		postInitError(id string, err error) (*http.Response, error)
+		postExitError(id string, err error) (*http.Response, error)
+	}
+)
+
+const (
+	envRuntimeDomain = "AWS_LAMBDA_RUNTIME_API"
+
+	headerExtensionName       = "Lambda-Extension-Name"
+	headerExtensionIdentifier = "Lambda-Extension-Identifier"
+	headerFunctionErrorType   = "Lambda-Extension-Function-Error-Type"
+
+	defaultInitErrorType = "Extension.InitError"
+	defaultExitErrorType = "Extension.ExitError"
+)
+
+var (
+	_ = api(defaultAPI{})
+)
+
+func newDefaultAPI(client httpClient) defaultAPI {
+	return newAPI(os.Getenv(envRuntimeDomain), client)
+}
+
+func newAPI(domain string, client httpClient) defaultAPI {
+	return defaultAPI{
+		domain:       domain,
+		registerUrl:  "http://" + domain + "/2020-01-01/extension/register",
+		nextUrl:      "http://" + domain + "/2020-01-01/extension/event/next",
+		initErrorUrl: "http://" + domain + "/2020-01-01/extension/init/error",
+		exitErrorUrl: "http://" + domain + "/2020-01-01/extension/exit/error",
+		client:       client,
+	}
+}
+
+func (api defaultAPI) register(name string, events []EventType) (string, RegisterResponse, error) {
+	body, _ := json.Marshal(struct {
+		Events []EventType `json:"events"`
+	}{
+		Events: events,
+	})
+
+	request, _ := http.NewRequest(
+		http.MethodPost,
+		api.registerUrl,
+		bytes.NewBuffer(body),
+	)
+	request.Header.Add(headerExtensionName, name)
+
+	resp, err := api.client.Do(request)
+	if err != nil {
+		return "", RegisterResponse{}, fmt.Errorf("%w; defaultAPI.register", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", RegisterResponse{}, fmt.Errorf("invalid status code (%d) defaultAPI.register for extension: %s\n%s", resp.StatusCode, name, string(data))
+	}
+
+	id := resp.Header.Get(headerExtensionIdentifier)
+	if id == "" {
+		return "", RegisterResponse{}, fmt.Errorf("defaultAPI.register for extension %s: no %s header in response", name, headerExtensionIdentifier)
+	}
+
+	registered := RegisterResponse{}
+	if err := json.Unmarshal(data, &registered); err != nil {
+		return "", RegisterResponse{}, fmt.Errorf("%w; defaultAPI.register response is not valid JSON", err)
+	}
+
+	return id, registered, nil
+}
+
+func (api defaultAPI) next(id string) (Event, error) {
+	request, _ := http.NewRequest(
+		http.MethodGet,
+		api.nextUrl,
+		nil,
+	)
+	request.Header.Add(headerExtensionIdentifier, id)
+
+	resp, err := api.client.Do(request)
+	if err != nil {
+		return Event{}, fmt.Errorf("%w; defaultAPI.next", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return Event{}, fmt.Errorf("invalid status code (%d) defaultAPI.next\n%s", resp.StatusCode, string(data))
+	}
+
+	event := Event{}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return Event{}, fmt.Errorf("%w; defaultAPI.next event is not valid JSON", err)
+	}
+
+	return event, nil
+}
+
+func (api defaultAPI) postInitError(id string, err error) (*http.Response, error) {
+	return api.postError(api.initErrorUrl, id, err, defaultInitErrorType)
+}
+
+func (api defaultAPI) postExitError(id string, err error) (*http.Response, error) {
+	return api.postError(api.exitErrorUrl, id, err, defaultExitErrorType)
+}
+
+func (api defaultAPI) postError(url, id string, err error, defaultType string) (*http.Response, error) {
+	payload := struct {
+		Message    string   `json:"errorMessage"`
+		Type       string   `json:"errorType"`
+		StackTrace []string `json:"stackTrace"`
+	}{
+		Message:    err.Error(),
+		Type:       defaultType,
+		StackTrace: []string{},
+	}
+
+	if err, ok := err.(llb.Error); ok {
+		payload.Type = err.Type()
+	}
+	if err, ok := err.(llb.StackTracer); ok {
+		payload.StackTrace = err.StackTrace()
+	}
+
+	body, _ := json.Marshal(payload)
+
+	request, _ := http.NewRequest(
+		http.MethodPost,
+		url,
+		bytes.NewBuffer(body),
+	)
+	request.Header.Add(headerExtensionIdentifier, id)
+	request.Header.Add(headerFunctionErrorType, payload.Type)
+
+	resp, err := api.client.Do(request)
+	if err != nil {
+		return resp, fmt.Errorf("%w; error submitting defaultAPI.postError request to %s", err, url)
+	}
+	defer resp.Body.Close()
+
+	msg, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusAccepted {
+		return resp, fmt.Errorf("invalid status code (%d) defaultAPI.postError to %s\n%s", resp.StatusCode, url, string(msg))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,242 @@
+package extension
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/RileyMcCuen/llb"
+)
+
+type (
+	mockHttpClient struct {
+		do func(r *http.Request) (*http.Response, error)
+	}
+)
+
+var (
+	_ = httpClient(mockHttpClient{})
+)
+
+func (client mockHttpClient) Do(r *http.Request) (*http.Response, error) {
+	return client.do(r)
+}
+
+func newResponse(status int, header http.Header, body string) *http.Response {
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(bytes.NewBufferString(body))}
+}
+
+func Test_newDefaultAPI(t *testing.T) {
+	os.Setenv(envRuntimeDomain, "domain")
+
+	got := newDefaultAPI(nil)
+
+	want := defaultAPI{
+		domain:       "domain",
+		registerUrl:  "http://domain/2020-01-01/extension/register",
+		nextUrl:      "http://domain/2020-01-01/extension/event/next",
+		initErrorUrl: "http://domain/2020-01-01/extension/init/error",
+		exitErrorUrl: "http://domain/2020-01-01/extension/exit/error",
+		client:       nil,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newDefaultAPI() = %v, want %v", got, want)
+	}
+}
+
+func Test_defaultAPI_register(t *testing.T) {
+	tests := []struct {
+		name    string
+		do      func(r *http.Request) (*http.Response, error)
+		wantId  string
+		want    RegisterResponse
+		wantErr bool
+	}{
+		{
+			name: "Success",
+			do: func(r *http.Request) (*http.Response, error) {
+				body, _ := io.ReadAll(r.Body)
+				if r.Header.Get(headerExtensionName) != "ext" || string(body) != `{"events":["INVOKE","SHUTDOWN"]}` {
+					return newResponse(http.StatusBadRequest, nil, ""), nil
+				}
+				return newResponse(http.StatusOK, http.Header{headerExtensionIdentifier: []string{"id"}}, `{"functionName":"fn","functionVersion":"$LATEST","handler":"bootstrap"}`), nil
+			},
+			wantId: "id",
+			want:   RegisterResponse{FunctionName: "fn", FunctionVersion: "$LATEST", Handler: "bootstrap"},
+		},
+		{
+			name:    "Request Error",
+			do:      func(r *http.Request) (*http.Response, error) { return nil, errors.New("error") },
+			wantErr: true,
+		},
+		{
+			name: "Status Error",
+			do: func(r *http.Request) (*http.Response, error) {
+				return newResponse(http.StatusForbidden, nil, "data"), nil
+			},
+			wantErr: true,
+		},
+		{
+			name: "Missing Identifier",
+			do: func(r *http.Request) (*http.Response, error) {
+				return newResponse(http.StatusOK, http.Header{}, "{}"), nil
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid Body",
+			do: func(r *http.Request) (*http.Response, error) {
+				return newResponse(http.StatusOK, http.Header{headerExtensionIdentifier: []string{"id"}}, "{"), nil
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, got, err := newAPI("domain", mockHttpClient{do: tt.do}).register("ext", []EventType{Invoke, Shutdown})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("defaultAPI.register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if id != tt.wantId || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("defaultAPI.register() = %s, %v, want %s, %v", id, got, tt.wantId, tt.want)
+			}
+		})
+	}
+}
+
+func Test_defaultAPI_next(t *testing.T) {
+	tests := []struct {
+		name    string
+		do      func(r *http.Request) (*http.Response, error)
+		want    Event
+		wantErr bool
+	}{
+		{
+			name: "Invoke",
+			do: func(r *http.Request) (*http.Response, error) {
+				if r.Header.Get(headerExtensionIdentifier) != "id" {
+					return newResponse(http.StatusForbidden, nil, ""), nil
+				}
+				return newResponse(http.StatusOK, nil, `{"eventType":"INVOKE","deadlineMs":100,"requestId":"req","invokedFunctionArn":"arn","tracing":{"type":"X-Amzn-Trace-Id","value":"Root=1"}}`), nil
+			},
+			want: Event{EventType: Invoke, DeadlineMs: 100, RequestId: "req", InvokedFunctionArn: "arn", Tracing: Tracing{Type: "X-Amzn-Trace-Id", Value: "Root=1"}},
+		},
+		{
+			name: "Shutdown",
+			do: func(r *http.Request) (*http.Response, error) {
+				return newResponse(http.StatusOK, nil, `{"eventType":"SHUTDOWN","deadlineMs":100,"shutdownReason":"spindown"}`), nil
+			},
+			want: Event{EventType: Shutdown, DeadlineMs: 100, ShutdownReason: "spindown"},
+		},
+		{
+			name:    "Request Error",
+			do:      func(r *http.Request) (*http.Response, error) { return nil, errors.New("error") },
+			wantErr: true,
+		},
+		{
+			name: "Status Error",
+			do: func(r *http.Request) (*http.Response, error) {
+				return newResponse(http.StatusInternalServerError, nil, "data"), nil
+			},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid Body",
+			do:      func(r *http.Request) (*http.Response, error) { return newResponse(http.StatusOK, nil, "{"), nil },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newAPI("domain", mockHttpClient{do: tt.do}).next("id")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("defaultAPI.next() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("defaultAPI.next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_defaultAPI_postError(t *testing.T) {
+	tests := []struct {
+		name     string
+		post     func(api defaultAPI) (*http.Response, error)
+		status   int
+		wantUrl  string
+		wantType string
+		wantErr  bool
+	}{
+		{
+			name:     "Init Error",
+			post:     func(api defaultAPI) (*http.Response, error) { return api.postInitError("id", errors.New("error")) },
+			status:   http.StatusAccepted,
+			wantUrl:  "http://domain/2020-01-01/extension/init/error",
+			wantType: defaultInitErrorType,
+		},
+		{
+			name:     "Exit Error",
+			post:     func(api defaultAPI) (*http.Response, error) { return api.postExitError("id", errors.New("error")) },
+			status:   http.StatusAccepted,
+			wantUrl:  "http://domain/2020-01-01/extension/exit/error",
+			wantType: defaultExitErrorType,
+		},
+		{
+			name: "Custom Error",
+			post: func(api defaultAPI) (*http.Response, error) {
+				return api.postExitError("id", llb.NewError(errors.New("error"), "header", "Extension.Custom"))
+			},
+			status:   http.StatusAccepted,
+			wantUrl:  "http://domain/2020-01-01/extension/exit/error",
+			wantType: "Extension.Custom",
+		},
+		{
+			name:     "Status Error",
+			post:     func(api defaultAPI) (*http.Response, error) { return api.postExitError("id", errors.New("error")) },
+			status:   http.StatusForbidden,
+			wantUrl:  "http://domain/2020-01-01/extension/exit/error",
+			wantType: defaultExitErrorType,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUrl, gotHeader string
+			payload := struct {
+				Type string `json:"errorType"`
+			}{}
+
+			api := newAPI("domain", mockHttpClient{do: func(r *http.Request) (*http.Response, error) {
+				gotUrl = r.URL.String()
+				gotHeader = r.Header.Get(headerFunctionErrorType)
+				body, _ := io.ReadAll(r.Body)
+				json.Unmarshal(body, &payload)
+				return newResponse(tt.status, nil, ""), nil
+			}})
+
+			if _, err := tt.post(api); (err != nil) != tt.wantErr {
+				t.Fatalf("defaultAPI.postError() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotUrl != tt.wantUrl {
+				t.Errorf("defaultAPI.postError() url = %s, want %s", gotUrl, tt.wantUrl)
+			}
+			if gotHeader != tt.wantType || payload.Type != tt.wantType {
+				t.Errorf("defaultAPI.postError() type = %s/%s, want %s", gotHeader, payload.Type, tt.wantType)
+			}
+		})
+	}
+}
+
+func Test_defaultAPI_postError_requestError(t *testing.T) {
+	api := newAPI("domain", mockHttpClient{do: func(r *http.Request) (*http.Response, error) { return nil, errors.New("error") }})
+	if _, err := api.postInitError("id", errors.New("error")); err == nil {
+		t.Fatal("defaultAPI.postInitError() did not return the request error")
+	}
+}
@@ -0,0 +1,146 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type (
+	EventType string
+
+	// Event is delivered by the Extensions API, RequestId, InvokedFunctionArn and Tracing are set for INVOKE events and ShutdownReason for SHUTDOWN events
+	Event struct {
+		EventType          EventType `json:"eventType"`
+		DeadlineMs         int64     `json:"deadlineMs"`
+		RequestId          string    `json:"requestId,omitempty"`
+		InvokedFunctionArn string    `json:"invokedFunctionArn,omitempty"`
+		Tracing            Tracing   `json:"tracing"`
+		ShutdownReason     string    `json:"shutdownReason,omitempty"`
+	}
+	Tracing struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+
+	// RegisterResponse describes the function the extension was registered with
+	RegisterResponse struct {
+		FunctionName    string `json:"functionName"`
+		FunctionVersion string `json:"functionVersion"`
+		Handler         string `json:"handler"`
+	}
+
+	// Handler is called for every event the extension registered for, ctx is cancelled at the event deadline; returning an error reports it to the exit error endpoint and stops the extension
+	Handler func(ctx context.Context, event Event) error
+
+	// Extension is a client of the Extensions API, it must be registered before it can receive events
+	Extension struct {
+		name   string
+		events []EventType
+		api    api
+		id     string
+	}
+)
+
+const (
+	Invoke   EventType = "INVOKE"
+	Shutdown EventType = "SHUTDOWN"
+)
+
+func defaultFatal(err error) {
+	panic(err)
+}
+
+// Start registers an extension called name for events (INVOKE and SHUTDOWN if none are given) and runs handler until the SHUTDOWN event, for external extensions name must be the file name of the extension executable
+func Start(name string, handler Handler, events ...EventType) {
+	ext := New(name, events...)
+
+	if _, err := ext.Register(); err != nil {
+		defaultFatal(err)
+	}
+
+	if err := ext.Run(handler); err != nil {
+		defaultFatal(err)
+	}
+}
+
+// New creates an unregistered Extension using the Extensions API at AWS_LAMBDA_RUNTIME_API, internal extensions may only register for INVOKE
+func New(name string, events ...EventType) *Extension {
+	return newExtension(name, events, newDefaultAPI(http.DefaultClient))
+}
+
+func newExtension(name string, events []EventType, api api) *Extension {
+	if len(events) == 0 {
+		events = []EventType{Invoke, Shutdown}
+	}
+
+	return &Extension{
+		name:   name,
+		events: events,
+		api:    api,
+	}
+}
+
+// Register registers the extension and stores the identifier used by every other call
+func (ext *Extension) Register() (RegisterResponse, error) {
+	id, resp, err := ext.api.register(ext.name, ext.events)
+	if err != nil {
+		return RegisterResponse{}, err
+	}
+
+	ext.id = id
+	return resp, nil
+}
+
+// Identifier returns the identifier assigned when the extension registered, it is empty before Register succeeds
+func (ext *Extension) Identifier() string { return ext.id }
+
+// Name returns the name the extension registers with
+func (ext *Extension) Name() string { return ext.name }
+
+// InitError reports err as the reason the extension failed to initialize, Lambda then fails the init phase
+func (ext *Extension) InitError(err error) error {
+	_, postErr := ext.api.postInitError(ext.id, err)
+	return postErr
+}
+
+// ExitError reports err as the reason the extension is exiting
+func (ext *Extension) ExitError(err error) error {
+	_, postErr := ext.api.postExitError(ext.id, err)
+	return postErr
+}
+
+// Run passes events to handler until the SHUTDOWN event has been handled, failures are reported with ExitError and returned
+func (ext *Extension) Run(handler Handler) error {
+	if ext.id == "" {
+		return fmt.Errorf("extension %s is not registered; Extension.Run", ext.name)
+	}
+
+	for {
+		event, err := ext.api.next(ext.id)
+		if err != nil {
+			ext.ExitError(err)
+			return err
+		}
+
+		if err := ext.handle(handler, event); err != nil {
+			ext.ExitError(err)
+			return err
+		}
+
+		if event.EventType == Shutdown {
+			return nil
+		}
+	}
+}
+
+func (ext *Extension) handle(handler Handler, event Event) error {
+	ctx, cancel := context.WithDeadline(context.Background(), event.Deadline())
+	defer cancel()
+
+	return handler(ctx, event)
+}
+
+// Deadline returns the time the event must be handled by
+func (e Event) Deadline() time.Time { return time.UnixMilli(e.DeadlineMs) }
@@ -0,0 +1,170 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type (
+	mockAPI struct {
+		_register      func(name string, events []EventType) (string, RegisterResponse, error)
+		_next          func(id string) (Event, error)
+		_postInitError func(id string, err error) (*http.Response, error)
+		_postExitError func(id string, err error) (*http.Response, error)
+	}
+)
+
+var (
+	_ = api(mockAPI{})
+)
+
+func (api mockAPI) register(name string, events []EventType) (string, RegisterResponse, error) {
+	return api._register(name, events)
+}
+
+func (api mockAPI) next(id string) (Event, error) {
+	return api._next(id)
+}
+
+func (api mockAPI) postInitError(id string, err error) (*http.Response, error) {
+	return api._postInitError(id, err)
+}
+
+func (api mockAPI) postExitError(id string, err error) (*http.Response, error) {
+	return api._postExitError(id, err)
+}
+
+func Test_newExtension(t *testing.T) {
+	ext := newExtension("ext", nil, nil)
+	if !reflect.DeepEqual(ext.events, []EventType{Invoke, Shutdown}) {
+		t.Errorf("newExtension() events = %v, want INVOKE and SHUTDOWN", ext.events)
+	}
+
+	ext = newExtension("ext", []EventType{Invoke}, nil)
+	if !reflect.DeepEqual(ext.events, []EventType{Invoke}) {
+		t.Errorf("newExtension() events = %v, want INVOKE", ext.events)
+	}
+}
+
+func TestExtension_Register(t *testing.T) {
+	ext := newExtension("ext", nil, mockAPI{
+		_register: func(name string, events []EventType) (string, RegisterResponse, error) {
+			return "id", RegisterResponse{FunctionName: name}, nil
+		},
+	})
+
+	resp, err := ext.Register()
+	if err != nil {
+		t.Fatalf("Extension.Register() error = %v", err)
+	}
+	if resp.FunctionName != "ext" || ext.Identifier() != "id" {
+		t.Errorf("Extension.Register() = %v, identifier %s", resp, ext.Identifier())
+	}
+
+	ext.api = mockAPI{
+		_register: func(name string, events []EventType) (string, RegisterResponse, error) {
+			return "", RegisterResponse{}, errors.New("error")
+		},
+	}
+	if _, err := ext.Register(); err == nil {
+		t.Error("Extension.Register() did not return the register error")
+	}
+}
+
+func TestExtension_Run(t *testing.T) {
+	deadline := time.Now().Add(time.Minute).UnixMilli()
+
+	tests := []struct {
+		name       string
+		id         string
+		events     []Event
+		nextErr    error
+		handlerErr error
+		wantCalls  int
+		wantExit   bool
+		wantErr    bool
+	}{
+		{
+			name:      "Until Shutdown",
+			id:        "id",
+			events:    []Event{{EventType: Invoke, DeadlineMs: deadline}, {EventType: Invoke, DeadlineMs: deadline}, {EventType: Shutdown, DeadlineMs: deadline}},
+			wantCalls: 3,
+		},
+		{
+			name:       "Handler Error",
+			id:         "id",
+			events:     []Event{{EventType: Invoke, DeadlineMs: deadline}},
+			handlerErr: errors.New("error"),
+			wantCalls:  1,
+			wantExit:   true,
+			wantErr:    true,
+		},
+		{
+			name:     "Next Error",
+			id:       "id",
+			nextErr:  errors.New("error"),
+			wantExit: true,
+			wantErr:  true,
+		},
+		{
+			name:    "Not Registered",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, exited := 0, false
+			ext := newExtension("ext", nil, mockAPI{
+				_next: func(id string) (Event, error) {
+					if tt.nextErr != nil {
+						return Event{}, tt.nextErr
+					}
+					event := tt.events[0]
+					tt.events = tt.events[1:]
+					return event, nil
+				},
+				_postExitError: func(id string, err error) (*http.Response, error) {
+					exited = true
+					return nil, nil
+				},
+			})
+			ext.id = tt.id
+
+			err := ext.Run(func(ctx context.Context, event Event) error {
+				calls++
+				if got, _ := ctx.Deadline(); !got.Equal(event.Deadline()) {
+					t.Errorf("handler context deadline = %v, want %v", got, event.Deadline())
+				}
+				return tt.handlerErr
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Extension.Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Extension.Run() handler calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if exited != tt.wantExit {
+				t.Errorf("Extension.Run() reported exit error = %v, want %v", exited, tt.wantExit)
+			}
+		})
+	}
+}
+
+func TestExtension_InitError(t *testing.T) {
+	var gotId string
+	ext := newExtension("ext", nil, mockAPI{
+		_postInitError: func(id string, err error) (*http.Response, error) {
+			gotId = id
+			return nil, nil
+		},
+	})
+	ext.id = "id"
+
+	if err := ext.InitError(errors.New("error")); err != nil || gotId != "id" {
+		t.Errorf("Extension.InitError() = %v, posted for %s", err, gotId)
+	}
+}
@@ -0,0 +1,29 @@
+package handlerutil
+
+import (
+	"encoding/json"
+)
+
+type (
+	// Codec decodes requests and encodes responses of typed handlers, ContentType is the Content-Type of the encoded responses; Decode may keep data, it is not reused by the handler
+	Codec interface {
+		Decode(data []byte, v any) error
+		Encode(v any) ([]byte, error)
+		ContentType() string
+	}
+
+	jsonCodec struct{}
+)
+
+const (
+	jsonContentType = "application/json"
+)
+
+var (
+	// JSONCodec uses encoding/json, it is the codec of InOutTypeHandler and InTypeHandler
+	JSONCodec = Codec(jsonCodec{})
+)
+
+func (jsonCodec) Decode(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Encode(v any) ([]byte, error)    { return json.Marshal(v) }
+func (jsonCodec) ContentType() string             { return jsonContentType }
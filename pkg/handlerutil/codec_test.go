@@ -0,0 +1,100 @@
+package handlerutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/RileyMcCuen/llb"
+	"github.com/RileyMcCuen/llb/pkg/llbtest"
+)
+
+type (
+	xmlCodec struct{}
+
+	// aliasCodec keeps the request bytes like zero-copy decoders do
+	aliasCodec struct{ kept *[][]byte }
+
+	greeting struct {
+		XMLName xml.Name `xml:"greeting"`
+		Name    string   `xml:"name"`
+	}
+)
+
+func (xmlCodec) Decode(data []byte, v any) error { return xml.Unmarshal(data, v) }
+func (xmlCodec) Encode(v any) ([]byte, error)    { return xml.Marshal(v) }
+func (xmlCodec) ContentType() string             { return "application/xml" }
+
+func (c aliasCodec) Decode(data []byte, v any) error { *c.kept = append(*c.kept, data); return nil }
+func (aliasCodec) Encode(v any) ([]byte, error)      { return nil, nil }
+func (aliasCodec) ContentType() string               { return "application/octet-stream" }
+
+func TestInTypeHandlerWithCodec_aliasing(t *testing.T) {
+	var kept [][]byte
+	handler := InTypeHandlerWithCodec(func(ctx context.Context, in struct{}) error { return nil }, aliasCodec{kept: &kept}, nil)
+
+	handler(context.Background(), bytes.NewBufferString("first"))
+	handler(context.Background(), bytes.NewBufferString("other"))
+
+	if len(kept) != 2 || string(kept[0]) != "first" || string(kept[1]) != "other" {
+		t.Errorf("InTypeHandlerWithCodec() decoded data changed after the handler returned: %q", kept)
+	}
+}
+
+func TestInOutTypeHandlerWithCodec(t *testing.T) {
+	handler := InOutTypeHandlerWithCodec(func(ctx context.Context, in greeting) (greeting, error) {
+		if in.Name == "" {
+			return greeting{}, errors.New("no name")
+		}
+		return greeting{Name: "hello " + in.Name}, nil
+	}, xmlCodec{}, nil)
+
+	out, err := handler(context.Background(), bytes.NewBufferString(`<greeting><name>llb</name></greeting>`))
+	if err != nil {
+		t.Fatalf("InOutTypeHandlerWithCodec() error = %v", err)
+	}
+
+	response, ok := out.(llb.Response)
+	if !ok || response.ContentType() != "application/xml" {
+		t.Fatalf("InOutTypeHandlerWithCodec() response = %T, want an llb.Response with the codec content type", out)
+	}
+	if data, _ := io.ReadAll(out); string(data) != `<greeting><name>hello llb</name></greeting>` {
+		t.Errorf("InOutTypeHandlerWithCodec() = %s", data)
+	}
+
+	if _, err := handler(context.Background(), bytes.NewBufferString(`{"name":"json"}`)); err == nil {
+		t.Error("InOutTypeHandlerWithCodec() did not report a decode error")
+	}
+}
+
+func TestInTypeHandlerWithCodec(t *testing.T) {
+	var got greeting
+	handler := InTypeHandlerWithCodec(func(ctx context.Context, in greeting) error {
+		got = in
+		return nil
+	}, xmlCodec{}, nil)
+
+	out, err := handler(context.Background(), bytes.NewBufferString(`<greeting><name>llb</name></greeting>`))
+	if out != nil || err != nil || got.Name != "llb" {
+		t.Errorf("InTypeHandlerWithCodec() = %v, %v, decoded %+v", out, err, got)
+	}
+}
+
+func TestJSONCodec_contentType(t *testing.T) {
+	server := llbtest.NewServer()
+	defer server.Close()
+
+	id := server.Invoke([]byte(`{"key":"value"}`))
+	server.Start(InOutTypeHandler(func(ctx context.Context, in map[string]string) (map[string]string, error) {
+		return in, nil
+	}, nil), llb.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+	result, ok := server.Result(id)
+	if !ok || result.ContentType != "application/json" || string(result.Response) != `{"key":"value"}` {
+		t.Errorf("posted response = %+v, %v", result, ok)
+	}
+}
@@ -0,0 +1,158 @@
+package handlerutil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/RileyMcCuen/llb"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type (
+	// SQSMessageHandler processes one message of an SQS batch, a returned error or a panic reports the message as a batch item failure
+	SQSMessageHandler func(ctx context.Context, message events.SQSMessage) error
+
+	// SQSBatchOption configures SQSBatchHandler and ProcessSQSBatch
+	SQSBatchOption func(*sqsBatchConfig)
+
+	sqsBatchConfig struct {
+		concurrency int
+		onFailure   func(ctx context.Context, message events.SQSMessage, err error)
+	}
+)
+
+const (
+	fifoQueueSuffix = ".fifo"
+)
+
+// SQSBatchHandler creates a Handler that runs handler for every message of an SQS batch and returns the failed messages as an events.SQSEventResponse, the event source mapping must have ReportBatchItemFailures enabled or the failures are ignored
+func SQSBatchHandler(handler SQSMessageHandler, errHandler llb.ErrorHandler, opts ...SQSBatchOption) llb.Handler {
+	return InOutTypeHandler(func(ctx context.Context, in events.SQSEvent) (events.SQSEventResponse, error) {
+		return ProcessSQSBatch(ctx, in, handler, opts...), nil
+	}, errHandler)
+}
+
+// WithConcurrency processes up to n messages at a time, defaults to 1; messages from a FIFO queue are always processed in order
+func WithConcurrency(n int) SQSBatchOption {
+	return func(cfg *sqsBatchConfig) {
+		cfg.concurrency = n
+	}
+}
+
+// OnMessageFailure sets the function called with every failed message and its error, defaults to logging with llb.Logger
+func OnMessageFailure(onFailure func(ctx context.Context, message events.SQSMessage, err error)) SQSBatchOption {
+	return func(cfg *sqsBatchConfig) {
+		cfg.onFailure = onFailure
+	}
+}
+
+func newSQSBatchConfig(opts []SQSBatchOption) sqsBatchConfig {
+	cfg := sqsBatchConfig{
+		concurrency: 1,
+		onFailure:   logMessageFailure,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.concurrency < 1 {
+		cfg.concurrency = 1
+	}
+
+	return cfg
+}
+
+func logMessageFailure(ctx context.Context, message events.SQSMessage, err error) {
+	llb.Logger(ctx).Error("sqs message failed", "messageId", message.MessageId, "error", err)
+}
+
+// ProcessSQSBatch runs handler for every message of event and returns the messages that failed, messages not started before ctx is done are failed without running handler
+func ProcessSQSBatch(ctx context.Context, event events.SQSEvent, handler SQSMessageHandler, opts ...SQSBatchOption) events.SQSEventResponse {
+	cfg := newSQSBatchConfig(opts)
+	failed := make([]bool, len(event.Records))
+
+	if isFIFOBatch(event) {
+		cfg.processInOrder(ctx, event.Records, handler, failed)
+	} else {
+		cfg.processConcurrently(ctx, event.Records, handler, failed)
+	}
+
+	response := events.SQSEventResponse{BatchItemFailures: []events.SQSBatchItemFailure{}}
+	for i, message := range event.Records {
+		if failed[i] {
+			response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: message.MessageId})
+		}
+	}
+
+	return response
+}
+
+// processInOrder stops at the first failure and fails the remaining messages, so a FIFO queue redelivers them in order
+func (cfg sqsBatchConfig) processInOrder(ctx context.Context, messages []events.SQSMessage, handler SQSMessageHandler, failed []bool) {
+	for i, message := range messages {
+		if ctx.Err() != nil || !cfg.process(ctx, message, handler) {
+			for j := i; j < len(failed); j++ {
+				failed[j] = true
+			}
+			return
+		}
+	}
+}
+
+func (cfg sqsBatchConfig) processConcurrently(ctx context.Context, messages []events.SQSMessage, handler SQSMessageHandler, failed []bool) {
+	slots := make(chan struct{}, cfg.concurrency)
+	var wg sync.WaitGroup
+
+	for i, message := range messages {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			failed[i] = true
+			continue
+		}
+
+		// the slot may have been free when ctx was already done
+		if ctx.Err() != nil {
+			<-slots
+			failed[i] = true
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, message events.SQSMessage) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			failed[i] = !cfg.process(ctx, message, handler)
+		}(i, message)
+	}
+
+	wg.Wait()
+}
+
+// process runs handler for message and reports whether it succeeded, a panic is recovered and reported as a failure
+func (cfg sqsBatchConfig) process(ctx context.Context, message events.SQSMessage, handler SQSMessageHandler) (ok bool) {
+	defer func() {
+		if v := recover(); v != nil {
+			cfg.onFailure(ctx, message, fmt.Errorf("panic: %v", v))
+			ok = false
+		}
+	}()
+
+	if err := handler(ctx, message); err != nil {
+		cfg.onFailure(ctx, message, err)
+		return false
+	}
+
+	return true
+}
+
+func isFIFOBatch(event events.SQSEvent) bool {
+	return len(event.Records) > 0 && strings.HasSuffix(event.Records[0].EventSourceARN, fifoQueueSuffix)
+}
@@ -0,0 +1,148 @@
+package handlerutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RileyMcCuen/llb/pkg/eventfixtures"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func sqsBatch(queueArn string, bodies ...string) events.SQSEvent {
+	builder := eventfixtures.SQS()
+	if queueArn != "" {
+		builder.WithQueueArn(queueArn)
+	}
+	for _, body := range bodies {
+		builder.WithMessage(body)
+	}
+
+	return builder.Build()
+}
+
+// failBodies fails messages with a body of "fail" and panics on a body of "panic"
+func failBodies(ctx context.Context, message events.SQSMessage) error {
+	switch message.Body {
+	case "fail":
+		return errors.New("failed")
+	case "panic":
+		panic("message panic")
+	}
+	return nil
+}
+
+func failedIds(event events.SQSEvent, indexes ...int) []events.SQSBatchItemFailure {
+	failures := []events.SQSBatchItemFailure{}
+	for _, i := range indexes {
+		failures = append(failures, events.SQSBatchItemFailure{ItemIdentifier: event.Records[i].MessageId})
+	}
+
+	return failures
+}
+
+func TestProcessSQSBatch(t *testing.T) {
+	fifoArn := "arn:aws:sqs:us-east-1:123456789012:my-queue.fifo"
+
+	tests := []struct {
+		name        string
+		event       events.SQSEvent
+		concurrency int
+		wantFailed  []int
+		// wantRun is how many failures reach OnMessageFailure, messages a FIFO batch skips after a failure are not run
+		wantRun int
+	}{
+		{name: "All Succeed", event: sqsBatch("", "a", "b", "c"), wantFailed: []int{}},
+		{name: "Errors", event: sqsBatch("", "a", "fail", "c", "fail"), wantFailed: []int{1, 3}, wantRun: 2},
+		{name: "Panic", event: sqsBatch("", "panic", "b"), wantFailed: []int{0}, wantRun: 1},
+		{name: "Concurrent", event: sqsBatch("", "fail", "b", "panic", "d", "fail"), concurrency: 3, wantFailed: []int{0, 2, 4}, wantRun: 3},
+		{name: "FIFO Stops At First Failure", event: sqsBatch(fifoArn, "a", "fail", "c", "d"), concurrency: 3, wantFailed: []int{1, 2, 3}, wantRun: 1},
+		{name: "Empty", event: events.SQSEvent{}, wantFailed: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reported atomic.Int64
+			got := ProcessSQSBatch(context.Background(), tt.event, failBodies,
+				WithConcurrency(tt.concurrency),
+				OnMessageFailure(func(ctx context.Context, message events.SQSMessage, err error) { reported.Add(1) }),
+			)
+
+			if want := failedIds(tt.event, tt.wantFailed...); !reflect.DeepEqual(got.BatchItemFailures, want) {
+				t.Errorf("ProcessSQSBatch() = %v, want %v", got.BatchItemFailures, want)
+			}
+			if reported.Load() != int64(tt.wantRun) {
+				t.Errorf("ProcessSQSBatch() reported %d failures, want %d", reported.Load(), tt.wantRun)
+			}
+		})
+	}
+}
+
+func TestProcessSQSBatch_concurrency(t *testing.T) {
+	var running, peak atomic.Int64
+	handler := func(ctx context.Context, message events.SQSMessage) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}
+
+	ProcessSQSBatch(context.Background(), sqsBatch("", "a", "b", "c", "d", "e", "f"), handler, WithConcurrency(2))
+
+	if peak.Load() != 2 {
+		t.Errorf("ProcessSQSBatch() ran %d messages at once, want 2", peak.Load())
+	}
+}
+
+func TestProcessSQSBatch_cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	event := sqsBatch("", "a", "b", "c")
+
+	calls := 0
+	got := ProcessSQSBatch(ctx, event, func(ctx context.Context, message events.SQSMessage) error {
+		calls++
+		cancel()
+		return nil
+	})
+
+	if want := failedIds(event, 1, 2); calls != 1 || !reflect.DeepEqual(got.BatchItemFailures, want) {
+		t.Errorf("ProcessSQSBatch() = %v after %d calls, want %v after 1", got.BatchItemFailures, calls, want)
+	}
+}
+
+func TestSQSBatchHandler(t *testing.T) {
+	event := sqsBatch("", "a", "panic", "fail")
+	handler := SQSBatchHandler(failBodies, nil, OnMessageFailure(func(context.Context, events.SQSMessage, error) {}))
+
+	out, err := handler(context.Background(), bytes.NewBuffer(eventfixtures.JSON(event)))
+	if err != nil {
+		t.Fatalf("SQSBatchHandler() error = %v", err)
+	}
+
+	data, _ := io.ReadAll(out)
+	got := events.SQSEventResponse{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("SQSBatchHandler() response %s: %v", data, err)
+	}
+	if want := failedIds(event, 1, 2); !reflect.DeepEqual(got.BatchItemFailures, want) {
+		t.Errorf("SQSBatchHandler() = %v, want %v", got.BatchItemFailures, want)
+	}
+
+	out, _ = handler(context.Background(), bytes.NewBuffer(eventfixtures.JSON(sqsBatch("", "a"))))
+	if data, _ := io.ReadAll(out); string(data) != `{"batchItemFailures":[]}` {
+		t.Errorf("SQSBatchHandler() = %s, want no failures", data)
+	}
+}
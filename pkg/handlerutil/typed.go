@@ -3,8 +3,9 @@ package handlerutil
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"fmt"
 	"io"
+	"sync"
 
 	"github.com/RileyMcCuen/llb"
 
@@ -17,33 +18,46 @@ type (
 	nothing                        struct{}
 )
 
+const (
+	// maxPooledBuffer is the capacity above which request buffers are dropped instead of pooled, so one large event does not stay in memory
+	maxPooledBuffer = 1 << 20
+)
+
 var (
 	Nothing = nothing{}
+
+	bufferPool = sync.Pool{New: func() any { return bytes.NewBuffer(nil) }}
 )
 
 // InTypeHandler creates a Handler from an InTypedHandler and an optional errHandler, if no errHandler is provided DefaultErrHandler is used instead
 func InTypeHandler[In any](handler func(ctx context.Context, in In) error, errHandler llb.ErrorHandler) llb.Handler {
-	return InOutTypeHandler(func(ctx context.Context, in In) (nothing, error) {
+	return InTypeHandlerWithCodec(handler, JSONCodec, errHandler)
+}
+
+// InTypeHandlerWithCodec creates a Handler from an InTypedHandler that decodes requests with codec
+func InTypeHandlerWithCodec[In any](handler func(ctx context.Context, in In) error, codec Codec, errHandler llb.ErrorHandler) llb.Handler {
+	return InOutTypeHandlerWithCodec(func(ctx context.Context, in In) (nothing, error) {
 		return Nothing, handler(ctx, in)
-	}, errHandler)
+	}, codec, errHandler)
 }
 
 // InOutTypeHandler creates a Handler from an InOutTypedHandler and an optional errHandler, if no errHandler is provided DefaultErrHandler is used instead
 func InOutTypeHandler[In any, Out any](handler InOutTypedHandler[In, Out], errHandler llb.ErrorHandler) llb.Handler {
+	return InOutTypeHandlerWithCodec(handler, JSONCodec, errHandler)
+}
+
+// InOutTypeHandlerWithCodec creates a Handler from an InOutTypedHandler that decodes requests and encodes responses with codec, responses carry the codec content type
+func InOutTypeHandlerWithCodec[In any, Out any](handler InOutTypedHandler[In, Out], codec Codec, errHandler llb.ErrorHandler) llb.Handler {
 	if errHandler == nil {
 		errHandler = llb.DefaultErrorHandler
 	}
 
 	_, nilOut := any(*new(Out)).(nothing)
-	buf := bytes.NewBuffer(nil)
 
 	return func(ctx context.Context, r io.Reader) (io.Reader, error) {
 		in := new(In)
 
-		buf.ReadFrom(r)
-		defer buf.Reset()
-
-		if err := json.Unmarshal(buf.Bytes(), in); err != nil {
+		if err := decodeRequest(r, codec, in); err != nil {
 			return errHandler(err)
 		}
 
@@ -56,15 +70,38 @@ func InOutTypeHandler[In any, Out any](handler InOutTypedHandler[In, Out], errHa
 			return nil, nil
 		}
 
-		data, err := json.Marshal(out)
+		data, err := codec.Encode(out)
 		if err != nil {
 			return errHandler(err)
 		}
 
-		return bytes.NewBuffer(data), nil
+		return llb.NewResponse(bytes.NewBuffer(data), codec.ContentType()), nil
 	}
 }
 
+// decodeRequest reads r into a pooled buffer and decodes it into v with codec, the handlers built by InOutTypeHandlerWithCodec may be called concurrently
+func decodeRequest(r io.Reader, codec Codec, v any) error {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			buf.Reset()
+			bufferPool.Put(buf)
+		}
+	}()
+
+	if _, err := buf.ReadFrom(r); err != nil {
+		return fmt.Errorf("%w; handlerutil.InOutTypeHandler failed reading the request", err)
+	}
+
+	data := buf.Bytes()
+	// encoding/json does not keep data, other codecs may alias it after the buffer is pooled again
+	if codec != JSONCodec {
+		data = bytes.Clone(data)
+	}
+
+	return codec.Decode(data, v)
+}
+
 func SNSHandler(handler func(ctx context.Context, in events.SNSEvent) error, errHandler llb.ErrorHandler) llb.Handler {
 	return InOutTypeHandler(func(ctx context.Context, in events.SNSEvent) (nothing, error) {
 		return Nothing, handler(ctx, in)
@@ -1 +1,141 @@
 package handlerutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RileyMcCuen/llb"
+)
+
+func TestInOutTypeHandlerMiddleware(t *testing.T) {
+	handler := llb.Chain(llb.RecoverMiddleware(), llb.MaxRequestSizeMiddleware(64))(
+		InOutTypeHandler(func(ctx context.Context, in map[string]string) (map[string]string, error) {
+			if in["panic"] != "" {
+				panic(in["panic"])
+			}
+			return in, nil
+		}, nil),
+	)
+
+	out, err := handler(context.Background(), bytes.NewBufferString(`{"key":"value"}`))
+	if data, _ := io.ReadAll(out); err != nil || string(data) != `{"key":"value"}` {
+		t.Errorf("wrapped InOutTypeHandler = %q, %v", data, err)
+	}
+
+	_, err = handler(context.Background(), bytes.NewBufferString(`{"panic":"boom"}`))
+	var llbErr llb.Error
+	if !errors.As(err, &llbErr) || llbErr.Type() != "Runtime.HandlerPanic" {
+		t.Errorf("wrapped InOutTypeHandler panic = %v, want Runtime.HandlerPanic", err)
+	}
+}
+
+type (
+	failingReader struct{}
+)
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
+
+func TestInOutTypeHandler_readError(t *testing.T) {
+	var handled error
+	handler := InOutTypeHandler(func(ctx context.Context, in map[string]string) (map[string]string, error) {
+		t.Error("handler was called after the request could not be read")
+		return in, nil
+	}, func(err error) (io.Reader, error) {
+		handled = err
+		return nil, err
+	})
+
+	if _, err := handler(context.Background(), failingReader{}); err == nil || handled == nil || !strings.Contains(handled.Error(), "read failed") {
+		t.Errorf("InOutTypeHandler() error = %v, ErrorHandler got %v", err, handled)
+	}
+}
+
+func TestInOutTypeHandler_concurrent(t *testing.T) {
+	handler := InOutTypeHandler(func(ctx context.Context, in map[string]int) (map[string]int, error) {
+		return in, nil
+	}, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				want := fmt.Sprintf(`{"n":%d}`, i*1000+j)
+				out, err := handler(context.Background(), strings.NewReader(want))
+				if data, _ := io.ReadAll(out); err != nil || string(data) != want {
+					t.Errorf("InOutTypeHandler() = %s, %v, want %s", data, err, want)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// sharedBufferHandler is the InOutTypeHandler implementation before buffers were pooled, it is only safe when calls are serial
+func sharedBufferHandler[In any, Out any](handler InOutTypedHandler[In, Out]) llb.Handler {
+	buf := bytes.NewBuffer(nil)
+
+	return func(ctx context.Context, r io.Reader) (io.Reader, error) {
+		in := new(In)
+
+		buf.ReadFrom(r)
+		defer buf.Reset()
+
+		if err := json.Unmarshal(buf.Bytes(), in); err != nil {
+			return nil, err
+		}
+
+		out, err := handler(ctx, *in)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(out)
+		if err != nil {
+			return nil, err
+		}
+
+		return bytes.NewBuffer(data), nil
+	}
+}
+
+func BenchmarkInOutTypeHandler(b *testing.B) {
+	event := []byte(`{"id":"1234567890","name":"benchmark","tags":["a","b","c"],"count":42}`)
+	typed := func(ctx context.Context, in map[string]any) (map[string]any, error) { return in, nil }
+
+	handlers := []struct {
+		name    string
+		handler llb.Handler
+	}{
+		{"SharedBuffer", sharedBufferHandler(typed)},
+		{"Pooled", InOutTypeHandler(typed, nil)},
+	}
+	for _, h := range handlers {
+		b.Run(h.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				h.handler(context.Background(), bytes.NewReader(event))
+			}
+		})
+	}
+
+	b.Run("PooledParallel", func(b *testing.B) {
+		handler := InOutTypeHandler(typed, nil)
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				handler(context.Background(), bytes.NewReader(event))
+			}
+		})
+	})
+}
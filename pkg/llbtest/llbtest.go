@@ -0,0 +1,380 @@
+package llbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RileyMcCuen/llb"
+)
+
+type (
+	// Event is an invocation served by the Server, zero fields get defaults when it is enqueued
+	Event struct {
+		Payload []byte
+		// RequestId defaults to request-N
+		RequestId string
+		// Deadline defaults to DefaultTimeout after the event is enqueued
+		Deadline time.Time
+		// FunctionArn defaults to DefaultFunctionArn
+		FunctionArn string
+		// TraceId defaults to a new unsampled X-Ray trace header
+		TraceId         string
+		ClientContext   string
+		CognitoIdentity string
+		// Header is added to the headers of the next invocation response, it may override the headers set from the other fields
+		Header http.Header
+	}
+
+	// ErrorPayload is an error reported to the Runtime API
+	ErrorPayload struct {
+		Message    string         `json:"errorMessage"`
+		Type       string         `json:"errorType"`
+		StackTrace []string       `json:"stackTrace"`
+		Cause      []ErrorPayload `json:"cause,omitempty"`
+	}
+
+	// Result is what the runtime reported for an invocation, Error is set when the invocation failed or a streamed response ended with an error
+	Result struct {
+		RequestId   string
+		Response    []byte
+		ContentType string
+		Streamed    bool
+		ErrorType   string
+		Error       *ErrorPayload
+	}
+
+	// Server is an in-process Runtime API, invocations are served in the order they were enqueued
+	Server struct {
+		server *httptest.Server
+
+		mu        sync.Mutex
+		queue     chan Event
+		pending   map[string]chan struct{}
+		results   map[string]Result
+		order     []string
+		initError *Result
+		fatal     []error
+		counter   int
+		closed    chan struct{}
+		closeOnce sync.Once
+	}
+)
+
+const (
+	DefaultTimeout     = 3 * time.Second
+	DefaultFunctionArn = "arn:aws:lambda:us-east-1:123456789012:function:llbtest"
+
+	headerRequestId       = "Lambda-Runtime-Aws-Request-Id"
+	headerDeadline        = "Lambda-Runtime-Deadline-Ms"
+	headerLambdaArn       = "Lambda-Runtime-Invoked-Function-Arn"
+	headerTraceId         = "Lambda-Runtime-Trace-Id"
+	headerClientContext   = "Lambda-Runtime-Client-Context"
+	headerCognitoIdentity = "Lambda-Runtime-Cognito-Identity"
+	headerContentType     = "Content-Type"
+	headerErrorType       = "Lambda-Runtime-Function-Error-Type"
+	headerErrorBody       = "Lambda-Runtime-Function-Error-Body"
+	headerResponseMode    = "Lambda-Runtime-Function-Response-Mode"
+
+	streamingResponseMode = "streaming"
+
+	pathNext             = "/2018-06-01/runtime/invocation/next"
+	pathInitError        = "/2018-06-01/runtime/init/error"
+	pathInvocationPrefix = "/2018-06-01/runtime/invocation/"
+
+	queueSize = 1024
+)
+
+var (
+	// ErrClosed is returned by Wait when the server is closed before the invocation has a result
+	ErrClosed = errors.New("llbtest server closed")
+)
+
+// NewServer starts a Runtime API server listening on a free local port, it must be closed with Close
+func NewServer() *Server {
+	s := &Server{
+		queue:   make(chan Event, queueSize),
+		pending: map[string]chan struct{}{},
+		results: map[string]Result{},
+		closed:  make(chan struct{}),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// Endpoint returns the host and port of the server, it is the value of AWS_LAMBDA_RUNTIME_API or llb.WithRuntimeAPI
+func (s *Server) Endpoint() string {
+	return strings.TrimPrefix(s.server.URL, "http://")
+}
+
+// Close unblocks pending polls and stops the server
+func (s *Server) Close() {
+	s.closeOnce.Do(func() { close(s.closed) })
+	s.server.CloseClientConnections()
+	s.server.Close()
+}
+
+// Enqueue adds events to the invocation queue and returns their request ids
+func (s *Server) Enqueue(events ...Event) []string {
+	ids := make([]string, 0, len(events))
+
+	for _, event := range events {
+		s.mu.Lock()
+		s.counter++
+		if event.RequestId == "" {
+			event.RequestId = "request-" + strconv.Itoa(s.counter)
+		}
+		s.pending[event.RequestId] = make(chan struct{})
+		s.mu.Unlock()
+
+		if event.Deadline.IsZero() {
+			event.Deadline = time.Now().Add(DefaultTimeout)
+		}
+		if event.FunctionArn == "" {
+			event.FunctionArn = DefaultFunctionArn
+		}
+		if event.TraceId == "" {
+			event.TraceId = newTraceId()
+		}
+
+		s.queue <- event
+		ids = append(ids, event.RequestId)
+	}
+
+	return ids
+}
+
+// Invoke enqueues an event with payload and returns its request id
+func (s *Server) Invoke(payload []byte) string {
+	return s.Enqueue(Event{Payload: payload})[0]
+}
+
+// Start runs handler with llb.StartWithOptions against the server until every enqueued event has been served, handler errors are recorded instead of stopping the runtime; opts are applied last
+func (s *Server) Start(handler llb.Handler, opts ...llb.Option) {
+	llb.StartWithOptions(handler, append(s.Options(), opts...)...)
+}
+
+// Options points the runtime at the server and stops it once the events waiting in the queue have been served, with an empty queue the runtime runs until the server is closed
+func (s *Server) Options() []llb.Option {
+	return []llb.Option{
+		llb.WithRuntimeAPI(s.Endpoint()),
+		llb.WithInvocationLimit(len(s.queue)),
+		llb.WithFatal(s.recordFatal),
+	}
+}
+
+// Result returns the result of the invocation requestId, ok is false until the runtime has reported it
+func (s *Server) Result(requestId string) (Result, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, ok := s.results[requestId]
+	return result, ok
+}
+
+// Results returns the results reported so far, in the order they were reported
+func (s *Server) Results() []Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]Result, 0, len(s.order))
+	for _, id := range s.order {
+		results = append(results, s.results[id])
+	}
+
+	return results
+}
+
+// Wait blocks until the invocation requestId has a result, ctx is done or the server is closed
+func (s *Server) Wait(ctx context.Context, requestId string) (Result, error) {
+	s.mu.Lock()
+	done, ok := s.pending[requestId]
+	s.mu.Unlock()
+
+	if !ok {
+		return Result{}, fmt.Errorf("unknown request %s; llbtest.Server.Wait", requestId)
+	}
+
+	select {
+	case <-done:
+		result, _ := s.Result(requestId)
+		return result, nil
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	case <-s.closed:
+		if result, ok := s.Result(requestId); ok {
+			return result, nil
+		}
+		return Result{}, ErrClosed
+	}
+}
+
+// InitError returns the error reported to the init error endpoint, ok is false if none was reported
+func (s *Server) InitError() (Result, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.initError == nil {
+		return Result{}, false
+	}
+
+	return *s.initError, true
+}
+
+// FatalErrors returns the errors the runtime passed to its fatal function when it was started with Start or Options
+func (s *Server) FatalErrors() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]error(nil), s.fatal...)
+}
+
+// recordFatal keeps the runtime serving after an error, once the server is closed it panics like the default fatal function so the runtime stops
+func (s *Server) recordFatal(err error) {
+	s.mu.Lock()
+	s.fatal = append(s.fatal, err)
+	s.mu.Unlock()
+
+	select {
+	case <-s.closed:
+		panic(err)
+	default:
+	}
+}
+
+// newTraceId returns an X-Ray trace header with a new root and parent
+func newTraceId() string {
+	random := make([]byte, 20)
+	rand.Read(random)
+
+	return fmt.Sprintf("Root=1-%08x-%x;Parent=%x;Sampled=0", time.Now().Unix(), random[:12], random[12:])
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == pathNext:
+		s.serveNext(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == pathInitError:
+		result := s.readError(r)
+		s.mu.Lock()
+		s.initError = &result
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, pathInvocationPrefix):
+		s.serveInvocation(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveNext blocks until an event is enqueued, like the Runtime API it does not time out
+func (s *Server) serveNext(w http.ResponseWriter, r *http.Request) {
+	var event Event
+	select {
+	case event = <-s.queue:
+	case <-r.Context().Done():
+		return
+	case <-s.closed:
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	header := w.Header()
+	header.Set(headerRequestId, event.RequestId)
+	header.Set(headerDeadline, strconv.FormatInt(event.Deadline.UnixMilli(), 10))
+	header.Set(headerLambdaArn, event.FunctionArn)
+	header.Set(headerTraceId, event.TraceId)
+	header.Set(headerContentType, "application/json")
+	if event.ClientContext != "" {
+		header.Set(headerClientContext, event.ClientContext)
+	}
+	if event.CognitoIdentity != "" {
+		header.Set(headerCognitoIdentity, event.CognitoIdentity)
+	}
+	for key, values := range event.Header {
+		header[http.CanonicalHeaderKey(key)] = values
+	}
+
+	w.Write(event.Payload)
+}
+
+// serveInvocation records the response or error posted for an invocation, a request that is unknown or already has a result is rejected with 400 like the Runtime API does
+func (s *Server) serveInvocation(w http.ResponseWriter, r *http.Request) {
+	requestId, kind, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, pathInvocationPrefix), "/")
+
+	var result Result
+	switch kind {
+	case "response":
+		result = s.readResponse(r)
+	case "error":
+		result = s.readError(r)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	result.RequestId = requestId
+
+	s.mu.Lock()
+	done, pending := s.pending[requestId]
+	_, reported := s.results[requestId]
+	if pending && !reported {
+		s.results[requestId] = result
+		s.order = append(s.order, requestId)
+		close(done)
+	}
+	s.mu.Unlock()
+
+	if !pending || reported {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"errorMessage":"invalid request id %s","errorType":"InvalidRequestID"}`, requestId)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) readResponse(r *http.Request) Result {
+	body, _ := io.ReadAll(r.Body)
+
+	result := Result{
+		Response:    body,
+		ContentType: r.Header.Get(headerContentType),
+		Streamed:    r.Header.Get(headerResponseMode) == streamingResponseMode,
+	}
+
+	// trailers are only complete once the body has been read
+	if errorType := r.Trailer.Get(headerErrorType); errorType != "" {
+		result.ErrorType = errorType
+		result.Error = &ErrorPayload{Type: errorType}
+
+		if data, err := base64.StdEncoding.DecodeString(r.Trailer.Get(headerErrorBody)); err == nil {
+			json.Unmarshal(data, result.Error)
+		}
+	}
+
+	return result
+}
+
+func (s *Server) readError(r *http.Request) Result {
+	payload := &ErrorPayload{}
+	body, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(body, payload); err != nil {
+		payload.Message = string(body)
+	}
+
+	return Result{
+		ErrorType: r.Header.Get(headerErrorType),
+		Error:     payload,
+	}
+}
@@ -0,0 +1,135 @@
+package llbtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RileyMcCuen/llb"
+)
+
+func quiet() llb.Option {
+	return llb.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestServer_Start(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	deadline := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	ids := server.Enqueue(
+		Event{Payload: []byte(`"hello"`), Deadline: deadline, ClientContext: `{"custom":{"key":"value"}}`, TraceId: "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1"},
+		Event{Payload: []byte(`"fail"`), RequestId: "failing"},
+		Event{Payload: []byte(`"stream"`), Header: http.Header{"X-Custom": {"custom"}}},
+	)
+
+	var metas []llb.RequestMeta
+	server.Start(func(ctx context.Context, r io.Reader) (io.Reader, error) {
+		meta := llb.MustRequestMeta(ctx)
+		metas = append(metas, meta)
+
+		payload, _ := io.ReadAll(r)
+		switch string(payload) {
+		case `"fail"`:
+			return nil, llb.NewError(errors.New("failed"), "Custom.Header", "Custom.Type")
+		case `"stream"`:
+			return llb.NewStreamingResponse(func(w io.Writer) error {
+				_, err := io.WriteString(w, "streamed")
+				return err
+			}, "text/plain"), nil
+		default:
+			return llb.NewResponse(bytes.NewReader(payload), "application/json"), nil
+		}
+	}, quiet(), llb.WithoutTraceEnv())
+
+	if len(metas) != 3 {
+		t.Fatalf("handler was invoked %d times, want 3", len(metas))
+	}
+	if metas[0].RequestId != ids[0] || !metas[0].Deadline.Equal(deadline) || !metas[0].Trace.IsSampled() {
+		t.Errorf("first RequestMeta = %+v", metas[0])
+	}
+	if cc, err := metas[0].ParseClientContext(); err != nil || cc.Custom["key"] != "value" {
+		t.Errorf("ParseClientContext() = %+v, %v", cc, err)
+	}
+	if metas[1].RequestId != "failing" || metas[2].LambdaArn != DefaultFunctionArn {
+		t.Errorf("RequestMeta = %+v, %+v", metas[1], metas[2])
+	}
+
+	if result, ok := server.Result(ids[0]); !ok || string(result.Response) != `"hello"` || result.ContentType != "application/json" || result.Error != nil {
+		t.Errorf("Result(%s) = %+v, %v", ids[0], result, ok)
+	}
+	if result, ok := server.Result("failing"); !ok || result.ErrorType != "Custom.Header" || result.Error.Type != "Custom.Type" || result.Error.Message != "failed" {
+		t.Errorf("Result(failing) = %+v, %v", result, ok)
+	}
+	if result, ok := server.Result(ids[2]); !ok || !result.Streamed || string(result.Response) != "streamed" {
+		t.Errorf("Result(%s) = %+v, %v", ids[2], result, ok)
+	}
+
+	if got := len(server.Results()); got != 3 {
+		t.Errorf("Results() returned %d results, want 3", got)
+	}
+	if got := len(server.FatalErrors()); got != 1 {
+		t.Errorf("FatalErrors() returned %d errors, want 1", got)
+	}
+}
+
+func TestServer_InitError(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Invoke([]byte(`{}`))
+
+	llb.StartWithInit(func(ctx context.Context) (llb.Handler, error) {
+		return nil, errors.New("init failed")
+	}, append(server.Options(), quiet())...)
+
+	result, ok := server.InitError()
+	if !ok || result.Error.Message != "init failed" || result.ErrorType != "Runtime.InitError" {
+		t.Errorf("InitError() = %+v, %v", result, ok)
+	}
+}
+
+func TestServer_Wait(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	id := server.Invoke([]byte(`{}`))
+	go server.Start(func(ctx context.Context, r io.Reader) (io.Reader, error) {
+		return strings.NewReader(`"done"`), nil
+	}, quiet())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := server.Wait(ctx, id)
+	if err != nil || string(result.Response) != `"done"` {
+		t.Errorf("Wait() = %+v, %v", result, err)
+	}
+
+	if _, err := server.Wait(ctx, "unknown"); err == nil {
+		t.Error("Wait() did not fail for an unknown request")
+	}
+}
+
+func TestServer_duplicateResult(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	id := server.Invoke([]byte(`{}`))
+	server.Start(func(ctx context.Context, r io.Reader) (io.Reader, error) { return nil, nil }, quiet())
+
+	resp, err := http.Post("http://"+server.Endpoint()+pathInvocationPrefix+id+"/response", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("second response status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,283 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RileyMcCuen/llb"
+)
+
+type (
+	// Unit is a CloudWatch metric unit
+	Unit string
+
+	Config struct {
+		// Namespace of the metrics, defaults to DefaultNamespace
+		Namespace string
+		// Dimensions are added to every metric along with FunctionName when the function name is known
+		Dimensions map[string]string
+		// Output receives one EMF document per invocation, defaults to os.Stdout
+		Output io.Writer
+	}
+
+	// recorder accumulates the metrics of one invocation, handlers may record from several goroutines
+	recorder struct {
+		mu         sync.Mutex
+		start      time.Time
+		namespace  string
+		dimensions map[string]string
+		properties map[string]any
+		metrics    map[string]*metric
+		order      []string
+	}
+
+	metric struct {
+		unit   Unit
+		values []float64
+	}
+
+	// document is an Embedded Metric Format log line, the metric values, dimensions and properties are top level members
+	document struct {
+		AWS     metadata
+		Members map[string]any
+	}
+	metadata struct {
+		Timestamp         int64       `json:"Timestamp"`
+		CloudWatchMetrics []directive `json:"CloudWatchMetrics"`
+	}
+	directive struct {
+		Namespace  string             `json:"Namespace"`
+		Dimensions [][]string         `json:"Dimensions"`
+		Metrics    []metricDefinition `json:"Metrics"`
+	}
+	metricDefinition struct {
+		Name string `json:"Name"`
+		Unit Unit   `json:"Unit,omitempty"`
+	}
+
+	recorderContextKey struct{}
+)
+
+const (
+	None         Unit = "None"
+	Count        Unit = "Count"
+	Milliseconds Unit = "Milliseconds"
+	Seconds      Unit = "Seconds"
+	Bytes        Unit = "Bytes"
+	Percent      Unit = "Percent"
+
+	DefaultNamespace = "llb"
+
+	// DurationMetric, ColdStartMetric and ErrorsMetric are recorded for every invocation
+	DurationMetric  = "Duration"
+	ColdStartMetric = "ColdStart"
+	ErrorsMetric    = "Errors"
+
+	dimensionFunctionName = "FunctionName"
+	propertyRequestId     = "RequestId"
+	propertyTraceId       = "TraceId"
+
+	// maxMetricsPerDirective is the CloudWatch limit on metrics in one EMF directive
+	maxMetricsPerDirective = 100
+)
+
+var (
+	recorderKey = recorderContextKey{}
+)
+
+// New returns an option that records metrics for every invocation and writes them as an EMF document once the invocation result has been posted
+func New(cfg Config) llb.Option {
+	cfg = withDefaults(cfg)
+
+	return llb.WithHooks(llb.Hooks{
+		BeforeInvoke: func(ctx context.Context) context.Context {
+			return context.WithValue(ctx, recorderKey, newRecorder(ctx, cfg))
+		},
+		AfterInvoke: func(ctx context.Context, err error) {
+			rec, ok := ctx.Value(recorderKey).(*recorder)
+			if !ok {
+				return
+			}
+
+			rec.finish(ctx, err)
+			rec.write(cfg.Output)
+		},
+	})
+}
+
+func withDefaults(cfg Config) Config {
+	if cfg.Namespace == "" {
+		cfg.Namespace = DefaultNamespace
+	}
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
+	}
+
+	return cfg
+}
+
+func newRecorder(ctx context.Context, cfg Config) *recorder {
+	rec := &recorder{
+		start:      time.Now(),
+		namespace:  cfg.Namespace,
+		dimensions: map[string]string{},
+		properties: map[string]any{},
+		metrics:    map[string]*metric{},
+	}
+
+	if info, ok := llb.GetFunctionInfo(ctx); ok && info.Name != "" {
+		rec.dimensions[dimensionFunctionName] = info.Name
+	}
+	for key, value := range cfg.Dimensions {
+		rec.dimensions[key] = value
+	}
+
+	if meta, ok := llb.GetRequestMeta(ctx); ok {
+		rec.properties[propertyRequestId] = meta.RequestId
+		if meta.TraceId != "" {
+			rec.properties[propertyTraceId] = meta.TraceId
+		}
+	}
+
+	return rec
+}
+
+// Put records value for the metric name in the invocation of ctx, values recorded several times for a name are all emitted; it does nothing outside of an invocation started with New
+func Put(ctx context.Context, name string, value float64, unit Unit) {
+	if rec, ok := ctx.Value(recorderKey).(*recorder); ok {
+		rec.put(name, value, unit)
+	}
+}
+
+// AddDimension adds a dimension to the metrics of the invocation of ctx
+func AddDimension(ctx context.Context, key, value string) {
+	if rec, ok := ctx.Value(recorderKey).(*recorder); ok {
+		rec.mu.Lock()
+		rec.dimensions[key] = value
+		rec.mu.Unlock()
+	}
+}
+
+// SetProperty adds a value to the EMF document of the invocation of ctx, properties are searchable in the logs but are not metrics
+func SetProperty(ctx context.Context, key string, value any) {
+	if rec, ok := ctx.Value(recorderKey).(*recorder); ok {
+		rec.mu.Lock()
+		rec.properties[key] = value
+		rec.mu.Unlock()
+	}
+}
+
+// SetNamespace overrides the namespace of the metrics of the invocation of ctx
+func SetNamespace(ctx context.Context, namespace string) {
+	if rec, ok := ctx.Value(recorderKey).(*recorder); ok {
+		rec.mu.Lock()
+		rec.namespace = namespace
+		rec.mu.Unlock()
+	}
+}
+
+func (rec *recorder) put(name string, value float64, unit Unit) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	m, ok := rec.metrics[name]
+	if !ok {
+		m = &metric{unit: unit}
+		rec.metrics[name] = m
+		rec.order = append(rec.order, name)
+	}
+
+	m.values = append(m.values, value)
+}
+
+// finish records the metrics every invocation has from the RequestMeta in ctx and the invocation error
+func (rec *recorder) finish(ctx context.Context, err error) {
+	rec.put(DurationMetric, float64(time.Since(rec.start))/float64(time.Millisecond), Milliseconds)
+
+	meta, _ := llb.GetRequestMeta(ctx)
+	rec.put(ColdStartMetric, boolValue(meta.ColdStart), Count)
+	rec.put(ErrorsMetric, boolValue(err != nil), Count)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// write writes one EMF document per maxMetricsPerDirective metrics to w
+func (rec *recorder) write(w io.Writer) error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	for i := 0; i < len(rec.order); i += maxMetricsPerDirective {
+		names := rec.order[i:min(i+maxMetricsPerDirective, len(rec.order))]
+
+		line, err := json.Marshal(rec.document(names))
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (rec *recorder) document(names []string) document {
+	keys := make([]string, 0, len(rec.dimensions))
+	members := make(map[string]any, len(rec.properties)+len(rec.dimensions)+len(names))
+
+	for key, value := range rec.properties {
+		members[key] = value
+	}
+	for key, value := range rec.dimensions {
+		keys = append(keys, key)
+		members[key] = value
+	}
+	sort.Strings(keys)
+
+	definitions := make([]metricDefinition, 0, len(names))
+	for _, name := range names {
+		m := rec.metrics[name]
+		definitions = append(definitions, metricDefinition{Name: name, Unit: m.unit})
+
+		if len(m.values) == 1 {
+			members[name] = m.values[0]
+		} else {
+			members[name] = m.values
+		}
+	}
+
+	return document{
+		AWS: metadata{
+			Timestamp: time.Now().UnixMilli(),
+			CloudWatchMetrics: []directive{{
+				Namespace:  rec.namespace,
+				Dimensions: [][]string{keys},
+				Metrics:    definitions,
+			}},
+		},
+		Members: members,
+	}
+}
+
+// MarshalJSON writes the members next to the _aws metadata at the top level of the document
+func (doc document) MarshalJSON() ([]byte, error) {
+	members := make(map[string]any, len(doc.Members)+1)
+	for key, value := range doc.Members {
+		members[key] = value
+	}
+	members["_aws"] = doc.AWS
+
+	return json.Marshal(members)
+}
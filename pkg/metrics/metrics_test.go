@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RileyMcCuen/llb"
+)
+
+func testContext() context.Context {
+	ctx := llb.ContextWithRequestMeta(context.Background(), llb.RequestMeta{RequestId: "req", TraceId: "trace", ColdStart: true})
+	return llb.ContextWithFunctionInfo(ctx, llb.FunctionInfo{Name: "fn"})
+}
+
+func decode(t *testing.T, line []byte) map[string]any {
+	t.Helper()
+
+	doc := map[string]any{}
+	if err := json.Unmarshal(line, &doc); err != nil {
+		t.Fatalf("invalid EMF document %q: %v", line, err)
+	}
+
+	return doc
+}
+
+func Test_recorder(t *testing.T) {
+	ctx := testContext()
+	rec := newRecorder(ctx, withDefaults(Config{Dimensions: map[string]string{"Stage": "prod"}}))
+	ctx = context.WithValue(ctx, recorderKey, rec)
+
+	Put(ctx, "Items", 3, Count)
+	Put(ctx, "Items", 4, Count)
+	AddDimension(ctx, "Tenant", "a")
+	SetProperty(ctx, "Path", "/users")
+	SetNamespace(ctx, "App")
+	rec.finish(ctx, errors.New("failed"))
+
+	buf := bytes.NewBuffer(nil)
+	if err := rec.write(buf); err != nil {
+		t.Fatalf("recorder.write() error = %v", err)
+	}
+	doc := decode(t, buf.Bytes())
+
+	directive := doc["_aws"].(map[string]any)["CloudWatchMetrics"].([]any)[0].(map[string]any)
+	if directive["Namespace"] != "App" {
+		t.Errorf("Namespace = %v, want App", directive["Namespace"])
+	}
+	if want := []any{[]any{"FunctionName", "Stage", "Tenant"}}; !reflect.DeepEqual(directive["Dimensions"], want) {
+		t.Errorf("Dimensions = %v, want %v", directive["Dimensions"], want)
+	}
+
+	var names []string
+	for _, m := range directive["Metrics"].([]any) {
+		names = append(names, m.(map[string]any)["Name"].(string))
+	}
+	if want := []string{"Items", DurationMetric, ColdStartMetric, ErrorsMetric}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Metrics = %v, want %v", names, want)
+	}
+
+	members := map[string]any{
+		"Items":         []any{3.0, 4.0},
+		ColdStartMetric: 1.0,
+		ErrorsMetric:    1.0,
+		"FunctionName":  "fn",
+		"Stage":         "prod",
+		"Tenant":        "a",
+		"Path":          "/users",
+		"RequestId":     "req",
+		"TraceId":       "trace",
+	}
+	for key, want := range members {
+		if !reflect.DeepEqual(doc[key], want) {
+			t.Errorf("document[%s] = %v, want %v", key, doc[key], want)
+		}
+	}
+	if _, ok := doc[DurationMetric].(float64); !ok {
+		t.Errorf("document[%s] = %v, want a number", DurationMetric, doc[DurationMetric])
+	}
+}
+
+func Test_recorder_write_split(t *testing.T) {
+	rec := newRecorder(context.Background(), withDefaults(Config{}))
+	for i := 0; i < maxMetricsPerDirective+1; i++ {
+		rec.put("m"+strconv.Itoa(i), 1, None)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	rec.write(buf)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("recorder.write() wrote %d documents, want 2", len(lines))
+	}
+	for i, want := range []int{maxMetricsPerDirective, 1} {
+		directive := decode(t, []byte(lines[i]))["_aws"].(map[string]any)["CloudWatchMetrics"].([]any)[0].(map[string]any)
+		if got := len(directive["Metrics"].([]any)); got != want {
+			t.Errorf("document %d has %d metrics, want %d", i, got, want)
+		}
+	}
+}
+
+func TestPut_noInvocation(t *testing.T) {
+	// recording outside of an invocation must not panic
+	ctx := context.Background()
+	Put(ctx, "Items", 1, Count)
+	AddDimension(ctx, "Tenant", "a")
+	SetProperty(ctx, "Path", "/")
+	SetNamespace(ctx, "App")
+}
+
+func TestNew(t *testing.T) {
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && polls.Add(1) == 1 {
+			w.Header().Set("Lambda-Runtime-Aws-Request-Id", "req")
+			w.Header().Set("Lambda-Runtime-Deadline-Ms", strconv.FormatInt(time.Now().Add(time.Minute).UnixMilli(), 10))
+			w.Header().Set("Lambda-Runtime-Invoked-Function-Arn", "arn")
+			w.Header().Set("Lambda-Runtime-Trace-Id", "Root=1-5759e988-bd862e3fe1be46a994272793")
+			return
+		}
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	buf := bytes.NewBuffer(nil)
+	llb.StartWithOptions(
+		func(ctx context.Context, r io.Reader) (io.Reader, error) {
+			Put(ctx, "Items", 2, Count)
+			return strings.NewReader("{}"), nil
+		},
+		llb.WithRuntimeAPI(strings.TrimPrefix(server.URL, "http://")),
+		llb.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		llb.WithFatal(func(err error) { panic(err) }),
+		llb.WithoutTraceEnv(),
+		New(Config{Namespace: "App", Output: buf}),
+	)
+
+	doc := decode(t, buf.Bytes())
+	if doc["Items"] != 2.0 || doc[ColdStartMetric] != 1.0 || doc[ErrorsMetric] != 0.0 || doc["RequestId"] != "req" {
+		t.Errorf("New() emitted %s", buf.String())
+	}
+}
@@ -0,0 +1,154 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/RileyMcCuen/llb"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type (
+	// Flusher is implemented by the SDK TracerProvider, ForceFlush exports every span that has ended
+	Flusher interface {
+		ForceFlush(ctx context.Context) error
+	}
+)
+
+const (
+	instrumentationName = "github.com/RileyMcCuen/llb/pkg/otel"
+	defaultSpanName     = "llb.invoke"
+
+	attrInvocationId  = attribute.Key("faas.invocation_id")
+	attrColdStart     = attribute.Key("faas.coldstart")
+	attrFaasName      = attribute.Key("faas.name")
+	attrFaasVersion   = attribute.Key("faas.version")
+	attrResourceId    = attribute.Key("cloud.resource_id")
+	attrCloudRegion   = attribute.Key("cloud.region")
+	attrCloudProvider = attribute.Key("cloud.provider")
+	attrErrorType     = attribute.Key("error.type")
+
+	// DefaultFlushTimeout bounds the flush of WithFlush, so a stalled exporter can not keep the runtime from polling
+	DefaultFlushTimeout = 2 * time.Second
+
+	xrayTraceIdVersion = "1"
+	xraySampled        = "1"
+)
+
+// Middleware starts a server span for every invocation using the RequestMeta and FunctionInfo in ctx, the X-Ray trace of the invocation becomes the remote parent of the span
+func Middleware(tp trace.TracerProvider) llb.Middleware {
+	tracer := tp.Tracer(instrumentationName)
+
+	return func(next llb.Handler) llb.Handler {
+		return func(ctx context.Context, r io.Reader) (io.Reader, error) {
+			meta, _ := llb.GetRequestMeta(ctx)
+			info, _ := llb.GetFunctionInfo(ctx)
+
+			if parent, ok := SpanContextFromTraceHeader(meta.Trace); ok {
+				ctx = trace.ContextWithRemoteSpanContext(ctx, parent)
+			}
+
+			name := info.Name
+			if name == "" {
+				name = defaultSpanName
+			}
+
+			ctx, span := tracer.Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attrInvocationId.String(meta.RequestId),
+					attrResourceId.String(meta.LambdaArn),
+					attrColdStart.Bool(meta.ColdStart),
+					attrFaasName.String(info.Name),
+					attrFaasVersion.String(info.Version),
+					attrCloudRegion.String(info.Region),
+					attrCloudProvider.String("aws"),
+				),
+			)
+			defer span.End()
+
+			out, err := next(ctx, r)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+
+				var llbErr llb.Error
+				if errors.As(err, &llbErr) {
+					span.SetAttributes(attrErrorType.String(llbErr.Type()))
+				}
+			}
+
+			return out, err
+		}
+	}
+}
+
+// WithFlush flushes f before the runtime polls for the next invocation, so spans are exported before Lambda freezes the environment; a flush is given DefaultFlushTimeout
+func WithFlush(f Flusher) llb.Option {
+	return WithFlushTimeout(f, DefaultFlushTimeout)
+}
+
+// WithFlushTimeout is WithFlush with a flush that is cancelled after timeout, a failed flush is logged and the runtime polls anyway
+func WithFlushTimeout(f Flusher, timeout time.Duration) llb.Option {
+	return llb.WithHooks(llb.Hooks{
+		BeforeNext: func() {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			if err := f.ForceFlush(ctx); err != nil {
+				llb.Logger(ctx).Error("flushing spans failed", "error", err)
+			}
+		},
+	})
+}
+
+// SpanContextFromTraceHeader converts an X-Ray trace header to a remote span context, ok is false when the header has no valid root and parent
+func SpanContextFromTraceHeader(th llb.TraceHeader) (trace.SpanContext, bool) {
+	// an X-Ray root is version-epoch-random, the W3C trace id is the epoch followed by the random part
+	parts := strings.Split(th.Root, "-")
+	if len(parts) != 3 || parts[0] != xrayTraceIdVersion {
+		return trace.SpanContext{}, false
+	}
+
+	traceId, err := trace.TraceIDFromHex(parts[1] + parts[2])
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+
+	spanId, err := trace.SpanIDFromHex(th.Parent)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+
+	cfg := trace.SpanContextConfig{
+		TraceID: traceId,
+		SpanID:  spanId,
+		Remote:  true,
+	}
+	if th.Sampled == xraySampled {
+		cfg.TraceFlags = trace.FlagsSampled
+	}
+
+	return trace.NewSpanContext(cfg), true
+}
+
+// TraceHeaderFromSpanContext converts a span context back to an X-Ray trace header, e.g. to propagate it to AWS services
+func TraceHeaderFromSpanContext(sc trace.SpanContext) llb.TraceHeader {
+	traceId := sc.TraceID().String()
+	sampled := "0"
+	if sc.IsSampled() {
+		sampled = xraySampled
+	}
+
+	return llb.TraceHeader{
+		Root:    xrayTraceIdVersion + "-" + traceId[:8] + "-" + traceId[8:],
+		Parent:  sc.SpanID().String(),
+		Sampled: sampled,
+	}
+}
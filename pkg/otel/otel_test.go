@@ -0,0 +1,212 @@
+package otel
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RileyMcCuen/llb"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	testRoot   = "1-5759e988-bd862e3fe1be46a994272793"
+	testParent = "53995c3f42cd8ad8"
+)
+
+func newTestContext() context.Context {
+	ctx := llb.ContextWithRequestMeta(context.Background(), llb.RequestMeta{
+		RequestId: "req",
+		LambdaArn: "arn",
+		ColdStart: true,
+		Trace:     llb.TraceHeader{Root: testRoot, Parent: testParent, Sampled: "1"},
+	})
+
+	return llb.ContextWithFunctionInfo(ctx, llb.FunctionInfo{Name: "fn", Version: "$LATEST", Region: "us-east-1"})
+}
+
+func attributeMap(attrs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := map[attribute.Key]attribute.Value{}
+	for _, attr := range attrs {
+		m[attr.Key] = attr.Value
+	}
+
+	return m
+}
+
+func TestMiddleware(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	var handlerSpan trace.SpanContext
+	handler := Middleware(tp)(func(ctx context.Context, r io.Reader) (io.Reader, error) {
+		handlerSpan = trace.SpanContextFromContext(ctx)
+		return bytes.NewBufferString("data"), nil
+	})
+
+	if _, err := handler(newTestContext(), nil); err != nil {
+		t.Fatalf("handler error = %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("exported %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+
+	if span.Name != "fn" || span.SpanKind != trace.SpanKindServer {
+		t.Errorf("span name = %s, kind = %v", span.Name, span.SpanKind)
+	}
+	if span.SpanContext.TraceID().String() != "5759e988bd862e3fe1be46a994272793" || span.Parent.SpanID().String() != testParent || !span.Parent.IsRemote() {
+		t.Errorf("span was not parented to the X-Ray trace: trace %s, parent %s", span.SpanContext.TraceID(), span.Parent.SpanID())
+	}
+	if handlerSpan.SpanID() != span.SpanContext.SpanID() {
+		t.Error("handler context does not carry the invocation span")
+	}
+
+	attrs := attributeMap(span.Attributes)
+	if attrs[attrInvocationId].AsString() != "req" || attrs[attrResourceId].AsString() != "arn" || !attrs[attrColdStart].AsBool() {
+		t.Errorf("span attributes = %v", span.Attributes)
+	}
+	if span.Status.Code != codes.Unset {
+		t.Errorf("span status = %v, want unset", span.Status)
+	}
+}
+
+func TestMiddlewareError(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	handler := Middleware(tp)(func(ctx context.Context, r io.Reader) (io.Reader, error) {
+		return nil, llb.NewError(errors.New("error"), "header", "Custom.Error")
+	})
+	handler(llb.ContextWithRequestMeta(context.Background(), llb.RequestMeta{RequestId: "req"}), nil)
+
+	span := exporter.GetSpans()[0]
+	if span.Name != defaultSpanName {
+		t.Errorf("span name = %s, want %s", span.Name, defaultSpanName)
+	}
+	if span.Status.Code != codes.Error || span.Status.Description != "error" {
+		t.Errorf("span status = %v, want error", span.Status)
+	}
+	if attrs := attributeMap(span.Attributes); attrs[attrErrorType].AsString() != "Custom.Error" {
+		t.Errorf("span error.type = %v", attrs[attrErrorType])
+	}
+	if span.Parent.IsValid() {
+		t.Error("span has a parent without an X-Ray trace")
+	}
+}
+
+type flusher struct {
+	flushed int
+	// stall blocks ForceFlush until ctx is done, like an exporter that does not answer
+	stall bool
+}
+
+func (f *flusher) ForceFlush(ctx context.Context) error {
+	f.flushed++
+	if f.stall {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func TestSpanContextFromTraceHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      llb.TraceHeader
+		wantOk      bool
+		wantSampled bool
+	}{
+		{name: "Sampled", header: llb.TraceHeader{Root: testRoot, Parent: testParent, Sampled: "1"}, wantOk: true, wantSampled: true},
+		{name: "Not Sampled", header: llb.TraceHeader{Root: testRoot, Parent: testParent, Sampled: "0"}, wantOk: true},
+		{name: "No Parent", header: llb.TraceHeader{Root: testRoot}},
+		{name: "Bad Root", header: llb.TraceHeader{Root: "2-abc", Parent: testParent}},
+		{name: "Bad Hex", header: llb.TraceHeader{Root: "1-5759e988-zzz", Parent: testParent}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := SpanContextFromTraceHeader(tt.header)
+			if ok != tt.wantOk {
+				t.Fatalf("SpanContextFromTraceHeader() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+			if sc.IsSampled() != tt.wantSampled {
+				t.Errorf("SpanContextFromTraceHeader() sampled = %v, want %v", sc.IsSampled(), tt.wantSampled)
+			}
+			if back := TraceHeaderFromSpanContext(sc); back.Root != tt.header.Root || back.Parent != tt.header.Parent {
+				t.Errorf("TraceHeaderFromSpanContext() = %v, want %v", back, tt.header)
+			}
+		})
+	}
+}
+
+func TestWithFlush(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	f := &flusher{}
+	llb.StartWithOptions(
+		func(ctx context.Context, r io.Reader) (io.Reader, error) { return nil, nil },
+		llb.WithRuntimeAPI(strings.TrimPrefix(server.URL, "http://")),
+		llb.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		llb.WithFatal(func(err error) { panic(err) }),
+		WithFlush(f),
+	)
+
+	if f.flushed != 1 {
+		t.Errorf("WithFlush flushed %d times before polling, want 1", f.flushed)
+	}
+}
+
+func TestWithFlushTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	logs := &bytes.Buffer{}
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(logs, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	f := &flusher{stall: true}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		llb.StartWithOptions(
+			func(ctx context.Context, r io.Reader) (io.Reader, error) { return nil, nil },
+			llb.WithRuntimeAPI(strings.TrimPrefix(server.URL, "http://")),
+			llb.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			llb.WithFatal(func(err error) { panic(err) }),
+			WithFlushTimeout(f, 10*time.Millisecond),
+		)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a stalled flush kept the runtime from polling")
+	}
+
+	if !strings.Contains(logs.String(), "flushing spans failed") || !strings.Contains(logs.String(), context.DeadlineExceeded.Error()) {
+		t.Errorf("WithFlushTimeout logged %q, want the failed flush", logs.String())
+	}
+}
@@ -0,0 +1,84 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+type (
+	httpClient interface {
+		Do(*http.Request) (*http.Response, error)
+	}
+	defaultAPI struct {
+		domain       string
+		subscribeUrl string
+		client       httpClient
+	}
+	api interface {
+		subscribe(id string, request subscribeRequest) (*http.Response, error)
+	}
+
+	subscribeRequest struct {
+		SchemaVersion string      `json:"schemaVersion"`
+		Destination   destination `json:"destination"`
+		Types         []Type      `json:"types"`
+		Buffering     Buffering   `json:"buffering"`
+	}
+	destination struct {
+		Protocol string `json:"protocol"`
+		URI      string `json:"URI"`
+	}
+)
+
+const (
+	envRuntimeDomain = "AWS_LAMBDA_RUNTIME_API"
+
+	headerExtensionIdentifier = "Lambda-Extension-Identifier"
+
+	schemaVersion       = "2022-12-13"
+	destinationProtocol = "HTTP"
+)
+
+var (
+	_ = api(defaultAPI{})
+)
+
+func newDefaultAPI(client httpClient) defaultAPI {
+	return newAPI(os.Getenv(envRuntimeDomain), client)
+}
+
+func newAPI(domain string, client httpClient) defaultAPI {
+	return defaultAPI{
+		domain:       domain,
+		subscribeUrl: "http://" + domain + "/2022-07-01/telemetry",
+		client:       client,
+	}
+}
+
+func (api defaultAPI) subscribe(id string, subscription subscribeRequest) (*http.Response, error) {
+	body, _ := json.Marshal(subscription)
+
+	request, _ := http.NewRequest(
+		http.MethodPut,
+		api.subscribeUrl,
+		bytes.NewBuffer(body),
+	)
+	request.Header.Add(headerExtensionIdentifier, id)
+
+	resp, err := api.client.Do(request)
+	if err != nil {
+		return resp, fmt.Errorf("%w; defaultAPI.subscribe", err)
+	}
+	defer resp.Body.Close()
+
+	msg, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return resp, fmt.Errorf("invalid status code (%d) defaultAPI.subscribe\n%s", resp.StatusCode, string(msg))
+	}
+
+	return resp, nil
+}
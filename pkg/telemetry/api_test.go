@@ -0,0 +1,90 @@
+package telemetry
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+)
+
+type (
+	mockHttpClient struct {
+		do func(r *http.Request) (*http.Response, error)
+	}
+)
+
+var (
+	_ = httpClient(mockHttpClient{})
+)
+
+func (client mockHttpClient) Do(r *http.Request) (*http.Response, error) {
+	return client.do(r)
+}
+
+func newResponse(status int) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(bytes.NewBufferString("data"))}
+}
+
+func Test_newDefaultAPI(t *testing.T) {
+	os.Setenv(envRuntimeDomain, "domain")
+
+	got := newDefaultAPI(nil)
+
+	want := defaultAPI{
+		domain:       "domain",
+		subscribeUrl: "http://domain/2022-07-01/telemetry",
+		client:       nil,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newDefaultAPI() = %v, want %v", got, want)
+	}
+}
+
+func Test_defaultAPI_subscribe(t *testing.T) {
+	request := subscribeRequest{
+		SchemaVersion: schemaVersion,
+		Destination:   destination{Protocol: destinationProtocol, URI: "http://sandbox.localdomain:4243"},
+		Types:         []Type{Platform},
+		Buffering:     Buffering{MaxItems: 10},
+	}
+
+	tests := []struct {
+		name    string
+		do      func(r *http.Request) (*http.Response, error)
+		wantErr bool
+	}{
+		{
+			name: "Success",
+			do: func(r *http.Request) (*http.Response, error) {
+				body, _ := io.ReadAll(r.Body)
+				want := `{"schemaVersion":"2022-12-13","destination":{"protocol":"HTTP","URI":"http://sandbox.localdomain:4243"},"types":["platform"],"buffering":{"maxItems":10}}`
+				if r.Method != http.MethodPut || r.Header.Get(headerExtensionIdentifier) != "id" || string(body) != want {
+					return newResponse(http.StatusBadRequest), nil
+				}
+				return newResponse(http.StatusOK), nil
+			},
+		},
+		{
+			name:    "Request Error",
+			do:      func(r *http.Request) (*http.Response, error) { return nil, errors.New("error") },
+			wantErr: true,
+		},
+		{
+			name:    "Status Error",
+			do:      func(r *http.Request) (*http.Response, error) { return newResponse(http.StatusForbidden), nil },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newAPI("domain", mockHttpClient{do: tt.do}).subscribe("id", request)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("defaultAPI.subscribe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
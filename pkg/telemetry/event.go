@@ -0,0 +1,129 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type (
+	// Event is a single Telemetry API record, decode Record with the method matching Type
+	Event struct {
+		Time   time.Time       `json:"time"`
+		Type   string          `json:"type"`
+		Record json.RawMessage `json:"record"`
+	}
+
+	Tracing struct {
+		SpanId string `json:"spanId,omitempty"`
+		Type   string `json:"type"`
+		Value  string `json:"value"`
+	}
+
+	// PlatformStart is the record of a platform.start event
+	PlatformStart struct {
+		RequestId string   `json:"requestId"`
+		Version   string   `json:"version,omitempty"`
+		Tracing   *Tracing `json:"tracing,omitempty"`
+	}
+
+	// PlatformReport is the record of a platform.report event
+	PlatformReport struct {
+		RequestId string        `json:"requestId"`
+		Status    string        `json:"status"`
+		ErrorType string        `json:"errorType,omitempty"`
+		Metrics   ReportMetrics `json:"metrics"`
+		Tracing   *Tracing      `json:"tracing,omitempty"`
+	}
+	// ReportMetrics are the invocation metrics of a platform.report event, InitDurationMs is only set for the first invocation after a cold start
+	ReportMetrics struct {
+		DurationMs        float64 `json:"durationMs"`
+		BilledDurationMs  int64   `json:"billedDurationMs"`
+		MemorySizeMB      int64   `json:"memorySizeMB"`
+		MaxMemoryUsedMB   int64   `json:"maxMemoryUsedMB"`
+		InitDurationMs    float64 `json:"initDurationMs,omitempty"`
+		RestoreDurationMs float64 `json:"restoreDurationMs,omitempty"`
+	}
+
+	// LogRecord is the record of a function or extension event, Message holds the whole line for text logs and the message field for JSON logs
+	LogRecord struct {
+		Timestamp string          `json:"timestamp,omitempty"`
+		Level     string          `json:"level,omitempty"`
+		RequestId string          `json:"requestId,omitempty"`
+		Message   string          `json:"message,omitempty"`
+		Raw       json.RawMessage `json:"-"`
+	}
+)
+
+const (
+	TypePlatformInitStart   = "platform.initStart"
+	TypePlatformInitReport  = "platform.initReport"
+	TypePlatformStart       = "platform.start"
+	TypePlatformRuntimeDone = "platform.runtimeDone"
+	TypePlatformReport      = "platform.report"
+	TypeFunction            = "function"
+	TypeExtension           = "extension"
+)
+
+// PlatformStart decodes the record of a platform.start event
+func (e Event) PlatformStart() (PlatformStart, error) {
+	record := PlatformStart{}
+	if err := e.decode(TypePlatformStart, &record); err != nil {
+		return PlatformStart{}, err
+	}
+
+	return record, nil
+}
+
+// PlatformReport decodes the record of a platform.report event
+func (e Event) PlatformReport() (PlatformReport, error) {
+	record := PlatformReport{}
+	if err := e.decode(TypePlatformReport, &record); err != nil {
+		return PlatformReport{}, err
+	}
+
+	return record, nil
+}
+
+// Log decodes the record of a function or extension event in either the text or JSON log format
+func (e Event) Log() (LogRecord, error) {
+	if e.Type != TypeFunction && e.Type != TypeExtension {
+		return LogRecord{}, fmt.Errorf("event type %s is not a log event; Event.Log", e.Type)
+	}
+
+	record := LogRecord{Raw: e.Record}
+
+	var text string
+	if err := json.Unmarshal(e.Record, &text); err == nil {
+		record.Message = text
+		return record, nil
+	}
+
+	if err := json.Unmarshal(e.Record, &record); err != nil {
+		return LogRecord{}, fmt.Errorf("%w; Event.Log record is neither text nor a JSON object", err)
+	}
+
+	return record, nil
+}
+
+func (e Event) decode(typ string, record any) error {
+	if e.Type != typ {
+		return fmt.Errorf("event type %s is not %s; Event.decode", e.Type, typ)
+	}
+
+	if err := json.Unmarshal(e.Record, record); err != nil {
+		return fmt.Errorf("%w; Event.decode %s record", err, typ)
+	}
+
+	return nil
+}
+
+// BilledDuration returns the billed duration of the invocation
+func (m ReportMetrics) BilledDuration() time.Duration {
+	return time.Duration(m.BilledDurationMs) * time.Millisecond
+}
+
+// InitDuration returns the init duration of a cold start invocation, it is zero for warm invocations
+func (m ReportMetrics) InitDuration() time.Duration {
+	return time.Duration(m.InitDurationMs * float64(time.Millisecond))
+}
@@ -0,0 +1,62 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const batch = `[
+	{"time":"2022-10-12T00:00:15.064Z","type":"platform.start","record":{"requestId":"req","version":"$LATEST","tracing":{"spanId":"span","type":"X-Amzn-Trace-Id","value":"Root=1"}}},
+	{"time":"2022-10-12T00:00:15.064Z","type":"function","record":"text line\n"},
+	{"time":"2022-10-12T00:00:15.064Z","type":"extension","record":{"timestamp":"2022-10-12T00:00:15.064Z","level":"INFO","requestId":"req","message":"json line"}},
+	{"time":"2022-10-12T00:00:15.064Z","type":"platform.report","record":{"requestId":"req","status":"success","metrics":{"durationMs":101.5,"billedDurationMs":102,"memorySizeMB":128,"maxMemoryUsedMB":30,"initDurationMs":250.25}}}
+]`
+
+func TestEventDecode(t *testing.T) {
+	events := []Event{}
+	if err := json.Unmarshal([]byte(batch), &events); err != nil {
+		t.Fatal(err)
+	}
+
+	start, err := events[0].PlatformStart()
+	if err != nil {
+		t.Fatalf("Event.PlatformStart() error = %v", err)
+	}
+	if want := (PlatformStart{RequestId: "req", Version: "$LATEST", Tracing: &Tracing{SpanId: "span", Type: "X-Amzn-Trace-Id", Value: "Root=1"}}); !reflect.DeepEqual(start, want) {
+		t.Errorf("Event.PlatformStart() = %v, want %v", start, want)
+	}
+
+	text, err := events[1].Log()
+	if err != nil || text.Message != "text line\n" {
+		t.Errorf("Event.Log() text = %v, %v", text, err)
+	}
+
+	structured, err := events[2].Log()
+	if err != nil || structured.Message != "json line" || structured.Level != "INFO" || structured.RequestId != "req" {
+		t.Errorf("Event.Log() json = %v, %v", structured, err)
+	}
+
+	report, err := events[3].PlatformReport()
+	if err != nil {
+		t.Fatalf("Event.PlatformReport() error = %v", err)
+	}
+	if report.Metrics.BilledDuration() != 102*time.Millisecond {
+		t.Errorf("ReportMetrics.BilledDuration() = %v", report.Metrics.BilledDuration())
+	}
+	if report.Metrics.InitDuration() != 250250*time.Microsecond {
+		t.Errorf("ReportMetrics.InitDuration() = %v", report.Metrics.InitDuration())
+	}
+}
+
+func TestEventDecodeWrongType(t *testing.T) {
+	event := Event{Type: TypePlatformStart, Record: json.RawMessage(`{}`)}
+
+	if _, err := event.PlatformReport(); err == nil {
+		t.Error("Event.PlatformReport() decoded a platform.start event")
+	}
+	if _, err := event.Log(); err == nil {
+		t.Error("Event.Log() decoded a platform.start event")
+	}
+}
@@ -0,0 +1,171 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/RileyMcCuen/llb/pkg/extension"
+)
+
+type (
+	// Type is a telemetry stream an extension can subscribe to
+	Type string
+
+	// Buffering controls how Lambda batches events before sending them to the listener, zero fields use the Lambda defaults
+	Buffering struct {
+		MaxItems  int `json:"maxItems,omitempty"`
+		MaxBytes  int `json:"maxBytes,omitempty"`
+		TimeoutMs int `json:"timeoutMs,omitempty"`
+	}
+
+	Config struct {
+		// Types defaults to platform, function and extension
+		Types     []Type
+		Buffering Buffering
+		// Port the listener binds to, defaults to DefaultPort, -1 picks a free port
+		Port int
+		// Host is the host Lambda sends events to, defaults to DefaultHost
+		Host string
+	}
+
+	// Handler receives every batch of events sent by Lambda, batches are handled one at a time
+	Handler func(ctx context.Context, events []Event)
+
+	// Subscriber receives telemetry events on a local HTTP listener until it is shut down
+	Subscriber struct {
+		server   *http.Server
+		listener net.Listener
+		handler  Handler
+		mu       sync.Mutex
+	}
+)
+
+const (
+	Platform      Type = "platform"
+	Function      Type = "function"
+	ExtensionLogs Type = "extension"
+
+	DefaultPort = 4243
+	DefaultHost = "sandbox.localdomain"
+)
+
+// Subscribe starts a listener for cfg and subscribes the registered extension ext to the Telemetry API, events are passed to handler
+func Subscribe(ext *extension.Extension, cfg Config, handler Handler) (*Subscriber, error) {
+	return subscribe(newDefaultAPI(http.DefaultClient), ext.Identifier(), cfg, handler)
+}
+
+func subscribe(api api, id string, cfg Config, handler Handler) (*Subscriber, error) {
+	if id == "" {
+		return nil, errors.New("extension is not registered; telemetry.Subscribe")
+	}
+	if handler == nil {
+		return nil, errors.New("handler is nil; telemetry.Subscribe")
+	}
+
+	cfg = withDefaults(cfg)
+
+	sub, err := listen(cfg.Port, handler)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = api.subscribe(id, subscribeRequest{
+		SchemaVersion: schemaVersion,
+		Destination: destination{
+			Protocol: destinationProtocol,
+			URI:      "http://" + net.JoinHostPort(cfg.Host, strconv.Itoa(sub.Port())),
+		},
+		Types:     cfg.Types,
+		Buffering: cfg.Buffering,
+	})
+	if err != nil {
+		sub.Shutdown(context.Background())
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+func withDefaults(cfg Config) Config {
+	if len(cfg.Types) == 0 {
+		cfg.Types = []Type{Platform, Function, ExtensionLogs}
+	}
+	if cfg.Port == 0 {
+		cfg.Port = DefaultPort
+	}
+	if cfg.Host == "" {
+		cfg.Host = DefaultHost
+	}
+
+	return cfg
+}
+
+// listen starts the receiver on port, port -1 picks a free port
+func listen(port int, handler Handler) (*Subscriber, error) {
+	if port < 0 {
+		port = 0
+	}
+
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return nil, fmt.Errorf("%w; telemetry.listen", err)
+	}
+
+	sub := &Subscriber{
+		listener: listener,
+		handler:  handler,
+	}
+	sub.server = &http.Server{Handler: sub}
+
+	go sub.server.Serve(listener)
+
+	return sub, nil
+}
+
+// Port returns the port the listener is bound to
+func (sub *Subscriber) Port() int {
+	return sub.listener.Addr().(*net.TCPAddr).Port
+}
+
+// ServeHTTP decodes a batch of events posted by Lambda and passes it to the handler, a panic in the handler is logged and answered with 500 so Lambda retries the batch
+func (sub *Subscriber) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events := []Event{}
+	if err := json.Unmarshal(data, &events); err != nil {
+		slog.Warn("invalid telemetry batch", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	defer func() {
+		if v := recover(); v != nil {
+			slog.Error("telemetry handler panicked", "panic", v)
+			http.Error(w, "telemetry handler panicked", http.StatusInternalServerError)
+		}
+	}()
+
+	sub.handler(r.Context(), events)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Shutdown stops the listener after in flight batches have been handled or ctx is done
+func (sub *Subscriber) Shutdown(ctx context.Context) error {
+	return sub.server.Shutdown(ctx)
+}
@@ -0,0 +1,121 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type (
+	mockAPI struct {
+		_subscribe func(id string, request subscribeRequest) (*http.Response, error)
+	}
+)
+
+var (
+	_ = api(mockAPI{})
+)
+
+func (api mockAPI) subscribe(id string, request subscribeRequest) (*http.Response, error) {
+	return api._subscribe(id, request)
+}
+
+func Test_withDefaults(t *testing.T) {
+	got := withDefaults(Config{})
+	want := Config{Types: []Type{Platform, Function, ExtensionLogs}, Port: DefaultPort, Host: DefaultHost}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("withDefaults() = %v, want %v", got, want)
+	}
+}
+
+func Test_subscribe(t *testing.T) {
+	var subscribed subscribeRequest
+	received := make(chan []Event, 1)
+
+	sub, err := subscribe(mockAPI{
+		_subscribe: func(id string, request subscribeRequest) (*http.Response, error) {
+			subscribed = request
+			return nil, nil
+		},
+	}, "id", Config{Port: -1, Host: "localhost", Buffering: Buffering{TimeoutMs: 25}}, func(ctx context.Context, events []Event) {
+		received <- events
+	})
+	if err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+	defer sub.Shutdown(context.Background())
+
+	uri := "http://localhost:" + strconv.Itoa(sub.Port())
+	if subscribed.Destination.URI != uri || subscribed.Buffering.TimeoutMs != 25 || subscribed.SchemaVersion != schemaVersion {
+		t.Errorf("subscribe() request = %+v", subscribed)
+	}
+
+	resp, err := http.Post(uri, "application/json", bytes.NewBufferString(batch))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("posting batch = %v, %v", resp, err)
+	}
+
+	if events := <-received; len(events) != 4 || events[3].Type != TypePlatformReport {
+		t.Errorf("handler received %v", events)
+	}
+
+	resp, _ = http.Post(uri, "application/json", bytes.NewBufferString("{"))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid batch status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func Test_subscribe_errors(t *testing.T) {
+	handler := func(ctx context.Context, events []Event) {}
+
+	if _, err := subscribe(nil, "", Config{Port: -1}, handler); err == nil {
+		t.Error("subscribe() did not fail for an unregistered extension")
+	}
+
+	_, err := subscribe(mockAPI{
+		_subscribe: func(id string, request subscribeRequest) (*http.Response, error) {
+			return nil, errors.New("error")
+		},
+	}, "id", Config{Port: -1}, handler)
+	if err == nil {
+		t.Error("subscribe() did not return the subscribe error")
+	}
+
+	if _, err := subscribe(mockAPI{}, "id", Config{Port: -1}, nil); err == nil {
+		t.Error("subscribe() did not fail for a nil handler")
+	}
+}
+
+func TestSubscriber_ServeHTTP_panic(t *testing.T) {
+	calls := 0
+	sub, err := subscribe(mockAPI{
+		_subscribe: func(id string, request subscribeRequest) (*http.Response, error) {
+			return nil, nil
+		},
+	}, "id", Config{Port: -1, Host: "localhost"}, func(ctx context.Context, events []Event) {
+		calls++
+		panic("boom")
+	})
+	if err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+	defer sub.Shutdown(context.Background())
+
+	client := http.Client{Timeout: 5 * time.Second}
+	uri := "http://localhost:" + strconv.Itoa(sub.Port())
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post(uri, "application/json", bytes.NewBufferString(batch))
+		if err != nil || resp.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("posting batch %d = %v, %v, want status %d", i, resp, err, http.StatusInternalServerError)
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+}
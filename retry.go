@@ -0,0 +1,171 @@
+package llb
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+type (
+	// RetryPolicy controls how Runtime API calls are retried, polling for the next invocation is retried on any retryable error or status while posts are only retried when the request never reached the Runtime API
+	RetryPolicy struct {
+		// MaxAttempts is the number of times a call is made, 1 or less disables retries
+		MaxAttempts int
+		// BaseDelay is doubled after every attempt up to MaxDelay, the actual delay is picked at random below it
+		BaseDelay time.Duration
+		MaxDelay  time.Duration
+		// RetryableStatus are the status codes of idempotent calls that are retried
+		RetryableStatus []int
+		// Retryable reports whether an error of an idempotent call is retried, defaults to IsRetryableError
+		Retryable func(err error) bool
+		// Stats is updated by every call if set
+		Stats *RetryStats
+	}
+
+	// RetryStats counts Runtime API calls, it is safe to read while the runtime is running
+	RetryStats struct {
+		// Attempts counts every request sent, including retries
+		Attempts atomic.Int64
+		// Retries counts requests sent again after a failed attempt
+		Retries atomic.Int64
+		// Exhausted counts calls that still failed after the last attempt
+		Exhausted atomic.Int64
+	}
+
+	retryClient struct {
+		client httpClient
+		policy RetryPolicy
+		sleep  func(time.Duration)
+	}
+)
+
+var (
+	_ = httpClient(retryClient{})
+)
+
+// DefaultRetryPolicy makes up to 3 attempts with a backoff starting at 50ms, gateway and throttling statuses are retried
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   50 * time.Millisecond,
+		MaxDelay:    time.Second,
+		RetryableStatus: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// IsRetryableError reports whether err is a transient connection error, e.g. a reset or refused connection or a timeout
+func IsRetryableError(err error) bool {
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isDialError reports whether err happened before the connection was established, so the request was never sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func newRetryClient(client httpClient, policy RetryPolicy) httpClient {
+	if policy.MaxAttempts <= 1 {
+		return client
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = IsRetryableError
+	}
+
+	return retryClient{client: client, policy: policy, sleep: time.Sleep}
+}
+
+func (rc retryClient) Do(req *http.Request) (*http.Response, error) {
+	idempotent := req.Method == http.MethodGet
+
+	for attempt := 1; ; attempt++ {
+		rc.count(func(stats *RetryStats) { stats.Attempts.Add(1) })
+
+		resp, err := rc.client.Do(req)
+
+		if !rc.shouldRetry(req, idempotent, resp, err) {
+			return resp, err
+		}
+		if attempt >= rc.policy.MaxAttempts {
+			rc.count(func(stats *RetryStats) { stats.Exhausted.Add(1) })
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		// the body of a post has been consumed by the failed attempt
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		rc.sleep(rc.policy.backoff(attempt))
+		rc.count(func(stats *RetryStats) { stats.Retries.Add(1) })
+	}
+}
+
+// shouldRetry reports whether the result of an attempt is retried, a post is only retried if its body can be sent again and it failed to connect
+func (rc retryClient) shouldRetry(req *http.Request, idempotent bool, resp *http.Response, err error) bool {
+	if !idempotent {
+		return err != nil && isDialError(err) && (req.Body == nil || req.GetBody != nil)
+	}
+
+	if err != nil {
+		return rc.policy.Retryable(err)
+	}
+
+	for _, status := range rc.policy.RetryableStatus {
+		if resp.StatusCode == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (rc retryClient) count(update func(stats *RetryStats)) {
+	if rc.policy.Stats != nil {
+		update(rc.policy.Stats)
+	}
+}
+
+// backoff returns the delay before the attempt after attempt, it is picked at random up to the exponential delay
+func (policy RetryPolicy) backoff(attempt int) time.Duration {
+	delay := policy.BaseDelay
+	for i := 1; i < attempt && (policy.MaxDelay <= 0 || delay < policy.MaxDelay); i++ {
+		delay *= 2
+	}
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(delay)) + 1)
+}
@@ -0,0 +1,144 @@
+package llb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func statusResponse(status int) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(bytes.NewBufferString("data"))}
+}
+
+func Test_retryClient_Do(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+	resetErr := &net.OpError{Op: "read", Err: syscall.ECONNRESET}
+
+	tests := []struct {
+		name         string
+		method       string
+		results      []error
+		statuses     []int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "Next Success", method: http.MethodGet, statuses: []int{200}, wantAttempts: 1},
+		{name: "Next Reset", method: http.MethodGet, results: []error{resetErr, nil}, statuses: []int{0, 200}, wantAttempts: 2},
+		{name: "Next Unavailable", method: http.MethodGet, statuses: []int{503, 503, 200}, wantAttempts: 3},
+		{name: "Next Exhausted", method: http.MethodGet, results: []error{resetErr, resetErr, resetErr}, wantAttempts: 3, wantErr: true},
+		{name: "Next Not Retryable Status", method: http.MethodGet, statuses: []int{500}, wantAttempts: 1},
+		{name: "Next Not Retryable Error", method: http.MethodGet, results: []error{errors.New("bad")}, wantAttempts: 1, wantErr: true},
+		{name: "Post Dial", method: http.MethodPost, results: []error{dialErr, nil}, statuses: []int{0, 202}, wantAttempts: 2},
+		{name: "Post Reset", method: http.MethodPost, results: []error{resetErr}, wantAttempts: 1, wantErr: true},
+		{name: "Post Unavailable", method: http.MethodPost, statuses: []int{503}, wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := &RetryStats{}
+			policy := DefaultRetryPolicy()
+			policy.Stats = stats
+
+			var bodies []string
+			attempt := 0
+			client := newRetryClient(mockHttpClient{do: func(r *http.Request) (*http.Response, error) {
+				defer func() { attempt++ }()
+
+				if r.Body != nil {
+					body, _ := io.ReadAll(r.Body)
+					bodies = append(bodies, string(body))
+				}
+				if attempt < len(tt.results) && tt.results[attempt] != nil {
+					return nil, tt.results[attempt]
+				}
+				return statusResponse(tt.statuses[attempt]), nil
+			}}, policy).(retryClient)
+			client.sleep = func(time.Duration) {}
+
+			var body io.Reader
+			if tt.method == http.MethodPost {
+				body = bytes.NewBufferString("payload")
+			}
+			req, _ := http.NewRequest(tt.method, "http://localhost/", body)
+
+			_, err := client.Do(req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("retryClient.Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempt != tt.wantAttempts || stats.Attempts.Load() != int64(tt.wantAttempts) {
+				t.Errorf("retryClient.Do() attempts = %d (stats %d), want %d", attempt, stats.Attempts.Load(), tt.wantAttempts)
+			}
+			if stats.Retries.Load() != int64(tt.wantAttempts-1) {
+				t.Errorf("retryClient.Do() retries = %d, want %d", stats.Retries.Load(), tt.wantAttempts-1)
+			}
+			for _, body := range bodies {
+				if body != "payload" {
+					t.Errorf("retryClient.Do() sent body %q", body)
+				}
+			}
+		})
+	}
+}
+
+func Test_retryClient_Do_unreplayable(t *testing.T) {
+	attempts := 0
+	client := newRetryClient(mockHttpClient{do: func(r *http.Request) (*http.Response, error) {
+		attempts++
+		return nil, &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+	}}, DefaultRetryPolicy())
+
+	// a streamed body can not be sent again
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost/", io.NopCloser(bytes.NewBufferString("payload")))
+	req.GetBody = nil
+
+	if _, err := client.Do(req); err == nil || attempts != 1 {
+		t.Errorf("retryClient.Do() error = %v after %d attempts, want an error after 1", err, attempts)
+	}
+}
+
+func Test_newRetryClient_disabled(t *testing.T) {
+	if _, ok := newRetryClient(mockHttpClient{}, RetryPolicy{MaxAttempts: 1}).(retryClient); ok {
+		t.Error("newRetryClient() wrapped the client with retries disabled")
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 30 * time.Millisecond}
+
+	for attempt, max := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 30 * time.Millisecond, 40: 30 * time.Millisecond} {
+		for i := 0; i < 100; i++ {
+			if d := policy.backoff(attempt); d <= 0 || d > max {
+				t.Fatalf("RetryPolicy.backoff(%d) = %v, want (0, %v]", attempt, d, max)
+			}
+		}
+	}
+
+	if d := (RetryPolicy{}).backoff(3); d != 0 {
+		t.Errorf("RetryPolicy{}.backoff() = %v, want 0", d)
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"Reset", &net.OpError{Op: "read", Err: syscall.ECONNRESET}, true},
+		{"Refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{"Unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"Timeout", &net.DNSError{IsTimeout: true}, true},
+		{"Other", errors.New("other"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryableError(tt.err); got != tt.want {
+				t.Errorf("IsRetryableError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
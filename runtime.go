@@ -1,12 +1,19 @@
 package llb
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -15,14 +22,49 @@ type (
 		api     api
 		handler Handler
 		meta    RequestMeta
+		info    FunctionInfo
 		fatal   func(error)
+		logger  *slog.Logger
+		hooks   []Hooks
+
+		deadlineMargin  time.Duration
+		crashOnPanic    bool
+		overflow        ResponseOverflowHandler
+		disableTraceEnv bool
+
+		init         InitFunc
+		initDuration time.Duration
+		warm         bool
+
+		shutdownHooks   []func(ctx context.Context)
+		shutdownTimeout time.Duration
+		shutdownOnce    sync.Once
+		stopping        atomic.Bool
+		exit            func(code int)
+
+		maxInvocations int
+		invocations    int
+
+		// reported is set while an error that has already been posted is passed to fatal, so recover does not post it again
+		reported bool
+	}
+	// handlerPanic is the Error of a recovered handler panic, the runtime reports it for the invocation and keeps serving
+	handlerPanic struct {
+		stackError
 	}
 )
 
 const (
 	MaxLambdaInvokeSize = 6291456
 
-	envTraceId = "_X_AMZN_TRACE_ID"
+	// DefaultShutdownTimeout bounds the context passed to shutdown hooks
+	DefaultShutdownTimeout = 500 * time.Millisecond
+
+	// DefaultDeadlineMargin is subtracted from the invocation deadline when building the handler context, leaving time to report a result before Lambda stops the environment
+	DefaultDeadlineMargin = 100 * time.Millisecond
+
+	envTraceId        = "_X_AMZN_TRACE_ID"
+	envDeadlineMargin = "LLB_DEADLINE_MARGIN"
 
 	headerRequestId       = "Lambda-Runtime-Aws-Request-Id"
 	headerDeadline        = "Lambda-Runtime-Deadline-Ms"
@@ -36,48 +78,226 @@ func defaultFatal(err error) {
 	panic(err)
 }
 
+// Start runs the Lambda runtime loop with handler and the default options, the deadline margin may be overridden with the LLB_DEADLINE_MARGIN environment variable (e.g. "250ms")
 func Start(handler Handler) {
-	newRuntime(handler, newDefaultAPI(http.DefaultClient), defaultFatal).start()
+	StartWithOptions(handler)
+}
+
+// StartWithOptions runs the Lambda runtime loop with handler, configured by opts
+func StartWithOptions(handler Handler, opts ...Option) {
+	newConfiguredRuntime(handler, opts).start()
+}
+
+// StartWithInit runs initFn before polling for invocations and serves the Handler it returns, an error returned by initFn is reported to the init error endpoint and stops the runtime
+func StartWithInit(initFn InitFunc, opts ...Option) {
+	rt := newConfiguredRuntime(nil, opts)
+	rt.init = initFn
+	rt.start()
+}
+
+func newConfiguredRuntime(handler Handler, opts []Option) *runtime {
+	cfg := newConfig(opts)
+
+	api := newAPI(cfg.endpoint, newRetryClient(cfg.client, cfg.retry), cfg.logger)
+	api.crashOnPanic = cfg.crashOnPanic
+
+	rt := newRuntime(handler, api, cfg.fatal)
+	rt.info = functionInfoFromEnv()
+	rt.logger = cfg.logger
+	rt.hooks = cfg.hooks
+	rt.deadlineMargin = cfg.deadlineMargin
+	rt.crashOnPanic = cfg.crashOnPanic
+	rt.overflow = cfg.overflow
+	rt.disableTraceEnv = cfg.traceEnvOff
+	rt.shutdownHooks = cfg.shutdownHooks
+	rt.shutdownTimeout = cfg.shutdownTimeout
+	rt.exit = os.Exit
+	rt.maxInvocations = cfg.maxInvocations
+
+	return rt
 }
 
 func newRuntime(handler Handler, api api, fatal func(error)) *runtime {
 	return &runtime{
-		api:     api,
-		handler: handler,
-		meta:    RequestMeta{},
-		fatal:   fatal,
+		api:            api,
+		handler:        handler,
+		meta:           RequestMeta{},
+		fatal:          fatal,
+		logger:         slog.Default(),
+		deadlineMargin: DefaultDeadlineMargin,
+
+		shutdownTimeout: DefaultShutdownTimeout,
 	}
 }
 
+func deadlineMarginFromEnv() time.Duration {
+	raw := os.Getenv(envDeadlineMargin)
+	if raw == "" {
+		return DefaultDeadlineMargin
+	}
+
+	margin, err := time.ParseDuration(raw)
+	if err != nil || margin < 0 {
+		slog.Warn("ignoring invalid deadline margin", "env", envDeadlineMargin, "value", raw, "default", DefaultDeadlineMargin)
+		return DefaultDeadlineMargin
+	}
+
+	return margin
+}
+
 func (rt *runtime) start() {
-	log.Printf("Start LLB Version %s", Version)
+	rt.logger.Info("starting runtime", "version", Version)
+
+	if len(rt.shutdownHooks) > 0 {
+		defer rt.watchSignals()()
+	}
 
 	defer rt.recover()
 
-	for {
+	if rt.init != nil {
+		if err := rt.initialize(); err != nil {
+			rt.api.postRuntimeInitError(err)
+			rt.fail(err)
+			return
+		}
+	}
+
+	for !rt.stopping.Load() {
+		// next reports its errors to the Runtime API before returning them
 		if err := rt.next(); err != nil {
-			rt.fatal(err)
+			rt.fail(err)
 		}
 
 		rt.reset()
+
+		if rt.invocations++; rt.maxInvocations > 0 && rt.invocations >= rt.maxInvocations {
+			rt.shutdown()
+		}
 	}
+
+	rt.shutdown()
 }
 
-func (rt *runtime) recover() {
-	if err := recover(); err != nil {
-		if err, ok := err.(error); ok {
-			if rt.meta.RequestId == "" {
-				_, initErr := rt.api.postRuntimeInitError(err)
-				log.Println("INIT ERROR", err, initErr)
-			} else {
-				_, invokeErr := rt.api.postRuntimeInvocationError(rt.meta.RequestId, err)
-				log.Println("INVOKE ERROR", err, invokeErr)
+// fail passes err to fatal after it has been reported to the Runtime API, if fatal panics recover only logs err
+func (rt *runtime) fail(err error) {
+	rt.reported = true
+	rt.fatal(err)
+	rt.reported = false
+}
+
+// initialize runs the init function and records how long it took, a panic in the init function is reported by recover
+func (rt *runtime) initialize() error {
+	start := time.Now()
+	handler, err := rt.init(ContextWithFunctionInfo(context.Background(), rt.info))
+	rt.initDuration = time.Since(start)
+
+	// returned as is so the type of a llb.Error reaches the init error endpoint
+	if err != nil {
+		return err
+	}
+
+	if handler == nil {
+		return errors.New("init function returned a nil handler; runtime.initialize")
+	}
+
+	rt.handler = handler
+	return nil
+}
+
+// watchSignals shuts the runtime down on SIGTERM or SIGINT and exits once the shutdown hooks are done, the returned function stops watching
+func (rt *runtime) watchSignals() func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			rt.logger.Info("shutting down", "signal", sig.String())
+			rt.shutdown()
+
+			// the loop may be blocked polling for an invocation that will never come
+			if rt.exit != nil {
+				rt.exit(0)
 			}
+		case <-stopped:
 		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(stopped)
 	}
 }
 
+// shutdown stops the loop from polling and runs the shutdown hooks once, concurrent callers wait for the hooks to finish
+func (rt *runtime) shutdown() {
+	rt.shutdownOnce.Do(func() {
+		rt.stopping.Store(true)
+
+		ctx, cancel := context.WithTimeout(context.Background(), rt.shutdownTimeout)
+		defer cancel()
+
+		for _, hook := range rt.shutdownHooks {
+			rt.runShutdownHook(ctx, hook)
+		}
+	})
+}
+
+func (rt *runtime) runShutdownHook(ctx context.Context, hook func(ctx context.Context)) {
+	defer func() {
+		if v := recover(); v != nil {
+			rt.logger.Error("shutdown hook panicked", "panic", v)
+		}
+	}()
+
+	hook(ctx)
+}
+
+func (rt *runtime) recover() {
+	if v := recover(); v != nil {
+		err := panicValueError(v)
+
+		if rt.reported {
+			withRequestMeta(rt.logger, rt.meta).Error("runtime stopped", "error", err)
+		} else if rt.meta.RequestId == "" {
+			err := withStack(asError(err, defaultInitErrorHeader), 3)
+			_, initErr := rt.api.postRuntimeInitError(err)
+			rt.logger.Error("init failed", "error", err, "postError", initErr)
+		} else {
+			err := withStack(asError(err, defaultInvokeErrorHeader), 3)
+			_, invokeErr := rt.api.postRuntimeInvocationError(rt.meta.RequestId, err)
+			withRequestMeta(rt.logger, rt.meta).Error("invocation failed", "error", err, "postError", invokeErr)
+		}
+	}
+}
+
+// panicValueError converts a recovered value to an error, values that are not errors are formatted with %v
+func panicValueError(v any) error {
+	if err, ok := v.(error); ok {
+		return err
+	}
+
+	return fmt.Errorf("panic: %v", v)
+}
+
+// asError returns err if it is already an Error, otherwise it wraps err with header as both the header and type
+func asError(err error, header string) Error {
+	if err, ok := err.(Error); ok {
+		return err
+	}
+
+	return NewError(err, header, header)
+}
+
+// next serves one invocation, a returned error stops the runtime; a response that could not be posted only does so if the invocation error could not be reported either, as Lambda would otherwise wait for the invocation until its deadline
 func (rt *runtime) next() error {
+	for _, hooks := range rt.hooks {
+		if hooks.BeforeNext != nil {
+			hooks.BeforeNext()
+		}
+	}
+
 	resp, err := rt.api.getRuntimeInvocationNext()
 
 	if err != nil {
@@ -90,19 +310,155 @@ func (rt *runtime) next() error {
 		return err
 	}
 
-	ctx := context.WithValue(context.Background(), contextKey, rt.meta)
+	if !rt.warm {
+		rt.meta.ColdStart = true
+		rt.meta.InitDuration = rt.initDuration
+		rt.warm = true
+	}
+
+	// the body and context outlive the handler call so a StreamingResponse can use them while it is posted
+	defer resp.Body.Close()
 
-	handlerResponse, err := rt.handler(ctx, resp.Body)
+	ctx, cancel := rt.context()
+	defer cancel()
 
-	resp.Body.Close()
+	ctx = rt.beforeInvoke(ctx)
+
+	handlerResponse, panicked, err := rt.invoke(ctx, resp.Body)
+
+	if !panicked && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = TimeoutError{Deadline: rt.meta.Deadline, Err: err}
+	}
 
 	if err != nil {
 		rt.api.postRuntimeInvocationError(rt.meta.RequestId, err)
+		rt.afterInvoke(ctx, err)
+
+		// a recovered panic has been reported for this invocation only, the runtime keeps serving
+		if panicked || isHandlerPanic(err) {
+			Logger(ctx).Error("handler panicked", "error", err)
+			return nil
+		}
+
 		return err
-	} else {
-		rt.api.postRuntimeInvocationResponse(rt.meta.RequestId, handlerResponse)
+	}
+
+	handlerResponse, err = rt.limitResponse(ctx, handlerResponse)
+	if err != nil {
+		rt.api.postRuntimeInvocationError(rt.meta.RequestId, err)
+		rt.afterInvoke(ctx, err)
 		return err
 	}
+
+	_, err = rt.api.postRuntimeInvocationResponse(rt.meta.RequestId, handlerResponse)
+	rt.afterInvoke(ctx, err)
+
+	// a stream that panicked has been reported in the error trailers
+	if isHandlerPanic(err) {
+		Logger(ctx).Error("handler panicked", "error", err)
+		return nil
+	}
+
+	// the invocation error was reported in place of the response, only a response that could not be reported at all stops the runtime
+	if isResponseReported(err) {
+		Logger(ctx).Error("response not delivered", "error", err)
+		return nil
+	}
+
+	return err
+}
+
+// isResponseReported reports whether err is a response post failure that was reported as the invocation error
+func isResponseReported(err error) bool {
+	var reported responseReportedError
+	return errors.As(err, &reported)
+}
+
+// limitResponse buffers response up to MaxLambdaInvokeSize, a larger response is passed to the overflow handler if one is set and reported as a ResponseSizeError otherwise; streamed responses are not buffered
+func (rt *runtime) limitResponse(ctx context.Context, response io.Reader) (io.Reader, error) {
+	if response == nil {
+		return nil, nil
+	}
+
+	if _, ok := response.(StreamingResponse); ok {
+		return response, nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	n, err := buf.ReadFrom(io.LimitReader(response, MaxLambdaInvokeSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w; runtime.limitResponse failed reading the handler response", err)
+	}
+
+	if n <= MaxLambdaInvokeSize {
+		if response, ok := response.(Response); ok {
+			return NewResponse(buf, response.ContentType()), nil
+		}
+
+		return buf, nil
+	}
+
+	if rt.overflow != nil {
+		return rt.overflow(ctx, io.MultiReader(buf, response))
+	}
+
+	rest, _ := io.Copy(io.Discard, response)
+
+	return nil, ResponseSizeError{Size: n + rest, Limit: MaxLambdaInvokeSize}
+}
+
+// invoke calls the handler, unless crashOnPanic is set a panic is recovered and returned as a Runtime.HandlerPanic Error
+func (rt *runtime) invoke(ctx context.Context, r io.Reader) (response io.Reader, panicked bool, err error) {
+	if !rt.crashOnPanic {
+		defer func() {
+			if v := recover(); v != nil {
+				response, panicked = nil, true
+				err = newHandlerPanic(v)
+			}
+		}()
+	}
+
+	response, err = rt.handler(ctx, r)
+	return response, false, err
+}
+
+// newHandlerPanic converts a value recovered from a handler into a Runtime.HandlerPanic Error with the stack of the panic, it must be called by the deferred function that recovered
+func newHandlerPanic(v any) error {
+	err := withStack(NewError(panicValueError(v), handlerPanicHeader, handlerPanicHeader), 4)
+	return handlerPanic{err.(stackError)}
+}
+
+// isHandlerPanic reports whether err comes from a recovered handler panic
+func isHandlerPanic(err error) bool {
+	var panicErr handlerPanic
+	return errors.As(err, &panicErr)
+}
+
+func (rt *runtime) beforeInvoke(ctx context.Context) context.Context {
+	for _, hooks := range rt.hooks {
+		if hooks.BeforeInvoke != nil {
+			ctx = hooks.BeforeInvoke(ctx)
+		}
+	}
+
+	return ctx
+}
+
+func (rt *runtime) afterInvoke(ctx context.Context, err error) {
+	for _, hooks := range rt.hooks {
+		if hooks.AfterInvoke != nil {
+			hooks.AfterInvoke(ctx, err)
+		}
+	}
+}
+
+// context builds the handler context for the current invocation, it carries the RequestMeta, FunctionInfo and request logger and is cancelled deadlineMargin before the invocation deadline
+func (rt *runtime) context() (context.Context, context.CancelFunc) {
+	ctx := ContextWithRequestMeta(context.Background(), rt.meta)
+	ctx = ContextWithFunctionInfo(ctx, rt.info)
+	ctx = ContextWithLogger(ctx, withRequestMeta(rt.logger, rt.meta))
+
+	return context.WithDeadline(ctx, rt.meta.Deadline.Add(-rt.deadlineMargin))
 }
 
 func (rt *runtime) reset() {
@@ -118,6 +474,12 @@ func (rt *runtime) updateMeta(resp *http.Response) error {
 		return fmt.Errorf("%w; lambdaRuntime.updateMeta", err)
 	}
 
+	// a trace id that is not in the X-Ray format is still passed through as TraceId
+	rt.meta.Trace, _ = ParseTraceHeader(rt.meta.TraceId)
+	if !rt.disableTraceEnv {
+		os.Setenv(envTraceId, rt.meta.TraceId)
+	}
+
 	rt.meta.RequestId, err = validateHeader(headers, headerRequestId)
 	if err != nil {
 		return fmt.Errorf("%w; lambdaRuntime.updateMeta", err)
@@ -160,13 +522,7 @@ func validateHeader(headers http.Header, key string) (string, error) {
 }
 
 func validateTraceId(headers http.Header) (string, error) {
-	traceId, err := validateHeader(headers, headerTraceId)
-	if err != nil {
-		return "", err
-	}
-	os.Setenv(envTraceId, traceId)
-
-	return traceId, nil
+	return validateHeader(headers, headerTraceId)
 }
 
 func validateDeadline(headers http.Header) (time.Time, error) {
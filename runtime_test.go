@@ -5,9 +5,14 @@ import (
 	"context"
 	"errors"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"reflect"
 	"strconv"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -16,7 +21,7 @@ func newValidNextResponse() *http.Response {
 	return &http.Response{
 		StatusCode: http.StatusOK,
 		Status:     strconv.FormatInt(http.StatusOK, 10),
-		Header:     http.Header{headerTraceId: []string{"trace"}, headerRequestId: []string{"req"}, headerDeadline: []string{"100"}, headerLambdaArn: []string{"arn"}},
+		Header:     http.Header{headerTraceId: []string{"trace"}, headerRequestId: []string{"req"}, headerDeadline: []string{strconv.FormatInt(time.Now().Add(time.Minute).UnixMilli(), 10)}, headerLambdaArn: []string{"arn"}},
 		Body:       io.NopCloser(bytes.NewBufferString(`{"datakey":"dataval"}`)),
 	}
 }
@@ -66,7 +71,7 @@ func Test_newRuntime(t *testing.T) {
 		args args
 		want *runtime
 	}{
-		{"Success", args{handler: nil, api: nil, fatal: nil}, &runtime{}},
+		{"Success", args{handler: nil, api: nil, fatal: nil}, &runtime{logger: slog.Default(), deadlineMargin: DefaultDeadlineMargin, shutdownTimeout: DefaultShutdownTimeout}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -165,6 +170,7 @@ func Test_runtime_start(t *testing.T) {
 				handler: tt.fields.handler,
 				meta:    tt.fields.meta,
 				fatal:   tt.fields.fatal,
+				logger:  slog.Default(),
 			}
 			rt.start()
 		})
@@ -300,6 +306,7 @@ func Test_runtime_next(t *testing.T) {
 				handler: tt.fields.handler,
 				meta:    tt.fields.meta,
 				fatal:   tt.fields.fatal,
+				logger:  slog.Default(),
 			}
 			if err := rt.next(); (err != nil) != tt.wantErr {
 				t.Errorf("runtime.next() error = %v, wantErr %v", err, tt.wantErr)
@@ -308,6 +315,85 @@ func Test_runtime_next(t *testing.T) {
 	}
 }
 
+func Test_runtime_next_deadline(t *testing.T) {
+	var reported error
+	rt := newRuntime(
+		func(ctx context.Context, r io.Reader) (io.Reader, error) {
+			<-ctx.Done()
+			return nil, nil
+		},
+		mockAPI{
+			_getRuntimeInvocationNext: func() (*http.Response, error) {
+				resp := newValidNextResponse()
+				resp.Header.Set(headerDeadline, strconv.FormatInt(time.Now().Add(50*time.Millisecond).UnixMilli(), 10))
+				return resp, nil
+			},
+			_postRuntimeInvocationError: func(requestId string, err error) (*http.Response, error) {
+				reported = err
+				return nil, nil
+			},
+		},
+		nil,
+	)
+	rt.deadlineMargin = 10 * time.Millisecond
+
+	err := rt.next()
+
+	var timeoutErr TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("runtime.next() error = %v, want TimeoutError", err)
+	}
+	if !errors.As(reported, &timeoutErr) {
+		t.Fatalf("reported error = %v, want TimeoutError", reported)
+	}
+	if timeoutErr.Type() != timeoutErrorHeader {
+		t.Errorf("TimeoutError.Type() = %s, want %s", timeoutErr.Type(), timeoutErrorHeader)
+	}
+}
+
+func Test_runtime_context(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+	rt := &runtime{meta: RequestMeta{RequestId: "req", Deadline: deadline}, info: FunctionInfo{Name: "fn"}, logger: slog.Default(), deadlineMargin: time.Second}
+
+	ctx, cancel := rt.context()
+	defer cancel()
+
+	got, ok := ctx.Deadline()
+	if !ok {
+		t.Fatal("runtime.context() has no deadline")
+	}
+	if want := deadline.Add(-time.Second); !got.Equal(want) {
+		t.Errorf("runtime.context() deadline = %v, want %v", got, want)
+	}
+	if meta := MustRequestMeta(ctx); meta.RequestId != "req" {
+		t.Errorf("runtime.context() RequestMeta = %v", meta)
+	}
+	if info := MustFunctionInfo(ctx); info.Name != "fn" {
+		t.Errorf("runtime.context() FunctionInfo = %v", info)
+	}
+}
+
+func Test_deadlineMarginFromEnv(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "Unset", value: "", want: DefaultDeadlineMargin},
+		{name: "Valid", value: "250ms", want: 250 * time.Millisecond},
+		{name: "Invalid", value: "abc", want: DefaultDeadlineMargin},
+		{name: "Negative", value: "-1s", want: DefaultDeadlineMargin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envDeadlineMargin, tt.value)
+			if got := deadlineMarginFromEnv(); got != tt.want {
+				t.Errorf("deadlineMarginFromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_runtime_reset(t *testing.T) {
 	type fields struct {
 		api     api
@@ -483,3 +569,536 @@ func Test_validateDeadline(t *testing.T) {
 		})
 	}
 }
+
+func Test_runtime_recover(t *testing.T) {
+	var reported error
+	rt := newRuntime(nil, mockAPI{
+		_postRuntimeInvocationError: func(requestId string, err error) (*http.Response, error) {
+			reported = err
+			return nil, nil
+		},
+	}, nil)
+	rt.meta.RequestId = "req"
+
+	func() {
+		defer rt.recover()
+		panic(errors.New("error"))
+	}()
+
+	tracer, ok := reported.(StackTracer)
+	if !ok {
+		t.Fatalf("runtime.recover() reported %v without a stack trace", reported)
+	}
+	if trace := tracer.StackTrace(); len(trace) == 0 || !strings.Contains(trace[0], "Test_runtime_recover") {
+		t.Errorf("runtime.recover() stack trace does not start at the panic: %v", trace)
+	}
+	if reported.(Error).Type() != defaultInvokeErrorHeader {
+		t.Errorf("runtime.recover() reported type %s, want %s", reported.(Error).Type(), defaultInvokeErrorHeader)
+	}
+}
+
+func Test_runtime_next_panic(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       any
+		wantMessage string
+	}{
+		{name: "Error", value: errors.New("error"), wantMessage: "error"},
+		{name: "String", value: "boom", wantMessage: "panic: boom"},
+		{name: "Struct", value: struct{ Code int }{Code: 1}, wantMessage: "panic: {1}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reported error
+			rt := newRuntime(
+				func(ctx context.Context, r io.Reader) (io.Reader, error) { panic(tt.value) },
+				mockAPI{
+					_getRuntimeInvocationNext: func() (*http.Response, error) {
+						return newValidNextResponse(), nil
+					},
+					_postRuntimeInvocationError: func(requestId string, err error) (*http.Response, error) {
+						reported = err
+						return nil, nil
+					},
+				},
+				nil,
+			)
+
+			if err := rt.next(); err != nil {
+				t.Fatalf("runtime.next() error = %v, want recovered panic", err)
+			}
+
+			var reportedErr Error
+			if !errors.As(reported, &reportedErr) || reportedErr.Type() != handlerPanicHeader {
+				t.Fatalf("reported error = %v, want %s", reported, handlerPanicHeader)
+			}
+			if reported.Error() != tt.wantMessage {
+				t.Errorf("reported message = %q, want %q", reported.Error(), tt.wantMessage)
+			}
+			if _, ok := reported.(StackTracer); !ok {
+				t.Error("reported panic has no stack trace")
+			}
+		})
+	}
+}
+
+func Test_runtime_start_recoverMiddleware(t *testing.T) {
+	var reported error
+	rt := newRuntime(
+		Chain(RecoverMiddleware())(func(ctx context.Context, r io.Reader) (io.Reader, error) { panic("boom") }),
+		mockAPI{
+			_getRuntimeInvocationNext: func() (*http.Response, error) {
+				return newValidNextResponse(), nil
+			},
+			_postRuntimeInvocationError: func(requestId string, err error) (*http.Response, error) {
+				reported = err
+				return nil, nil
+			},
+		},
+		func(err error) { t.Errorf("runtime.fatal() called with %v", err) },
+	)
+	rt.maxInvocations = 2
+
+	rt.start()
+
+	if rt.invocations != 2 {
+		t.Errorf("runtime.start() served %d invocations, want 2", rt.invocations)
+	}
+	if !isHandlerPanic(reported) {
+		t.Errorf("reported error = %v, want %s", reported, handlerPanicHeader)
+	}
+}
+
+func Test_runtime_start_responseRejected(t *testing.T) {
+	var reported string
+	api := newDefaultAPI(mockHttpClient{do: func(r *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(r.URL.Path, "/error") {
+			data, _ := io.ReadAll(r.Body)
+			reported = string(data)
+			return &http.Response{StatusCode: http.StatusAccepted, Body: io.NopCloser(bytes.NewBufferString(""))}, nil
+		}
+		return &http.Response{StatusCode: http.StatusRequestEntityTooLarge, Body: io.NopCloser(bytes.NewBufferString("too large"))}, nil
+	}})
+
+	rt := newRuntime(
+		func(ctx context.Context, r io.Reader) (io.Reader, error) { return bytes.NewBufferString("{}"), nil },
+		mockAPI{
+			_getRuntimeInvocationNext: func() (*http.Response, error) {
+				return newValidNextResponse(), nil
+			},
+			_postRuntimeInvocationResponse: api.postRuntimeInvocationResponse,
+		},
+		func(err error) { t.Errorf("runtime.fatal() called with %v", err) },
+	)
+	rt.maxInvocations = 2
+
+	rt.start()
+
+	if rt.invocations != 2 {
+		t.Errorf("runtime.start() served %d invocations, want 2", rt.invocations)
+	}
+	if !strings.Contains(reported, "status code (413)") {
+		t.Errorf("reported invocation error = %s, want the response status", reported)
+	}
+}
+
+func Test_runtime_start_responseLost(t *testing.T) {
+	lost := errors.New("connection reset")
+	var fatal []error
+
+	rt := newRuntime(
+		func(ctx context.Context, r io.Reader) (io.Reader, error) { return bytes.NewBufferString("{}"), nil },
+		mockAPI{
+			_getRuntimeInvocationNext: func() (*http.Response, error) {
+				return newValidNextResponse(), nil
+			},
+			_postRuntimeInvocationResponse: func(requestId string, response io.Reader) (*http.Response, error) {
+				return nil, lost
+			},
+		},
+		func(err error) { fatal = append(fatal, err) },
+	)
+	rt.maxInvocations = 1
+
+	rt.start()
+
+	if len(fatal) != 1 || !errors.Is(fatal[0], lost) {
+		t.Errorf("runtime.fatal() called with %v, want the unreported response error", fatal)
+	}
+}
+
+func Test_runtime_next_crashOnPanic(t *testing.T) {
+	rt := newRuntime(
+		func(ctx context.Context, r io.Reader) (io.Reader, error) { panic("boom") },
+		mockAPI{
+			_getRuntimeInvocationNext: func() (*http.Response, error) {
+				return newValidNextResponse(), nil
+			},
+		},
+		nil,
+	)
+	rt.crashOnPanic = true
+
+	defer func() {
+		if v := recover(); v != "boom" {
+			t.Fatalf("runtime.next() recovered = %v, want boom", v)
+		}
+	}()
+
+	rt.next()
+}
+
+func Test_runtime_next_streamPanic(t *testing.T) {
+	var gotTrailer http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		gotTrailer = r.Trailer
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	api := newAPI(strings.TrimPrefix(server.URL, "http://"), server.Client(), slog.Default())
+	rt := newRuntime(
+		NewStreamingHandler(func(ctx context.Context, r io.Reader, w io.Writer) error { panic("boom") }, "text/plain"),
+		mockAPI{
+			_getRuntimeInvocationNext: func() (*http.Response, error) {
+				return newValidNextResponse(), nil
+			},
+			_postRuntimeInvocationResponse: api.postRuntimeInvocationResponse,
+		},
+		func(err error) { t.Fatalf("runtime.fatal() called with %v", err) },
+	)
+
+	if err := rt.next(); err != nil {
+		t.Fatalf("runtime.next() error = %v, want recovered panic", err)
+	}
+	if got := gotTrailer.Get(headerErrorType); got != handlerPanicHeader {
+		t.Errorf("error type trailer = %q, want %s", got, handlerPanicHeader)
+	}
+}
+
+func Test_panicValueError(t *testing.T) {
+	err := errors.New("error")
+	if got := panicValueError(err); got != err {
+		t.Errorf("panicValueError() = %v, want %v", got, err)
+	}
+	if got := panicValueError(1); got.Error() != "panic: 1" {
+		t.Errorf("panicValueError() = %v, want panic: 1", got)
+	}
+}
+
+func Test_runtime_limitResponse(t *testing.T) {
+	overflow := func(ctx context.Context, r io.Reader) (io.Reader, error) {
+		n, _ := io.Copy(io.Discard, r)
+		return bytes.NewBufferString(strconv.FormatInt(n, 10)), nil
+	}
+
+	tests := []struct {
+		name            string
+		response        io.Reader
+		overflow        ResponseOverflowHandler
+		want            string
+		wantContentType string
+		wantSize        int64
+	}{
+		{name: "Nil", response: nil},
+		{name: "Within Limit", response: bytes.NewBufferString("data"), want: "data"},
+		{name: "Within Limit Response", response: NewResponse(bytes.NewBufferString("data"), "text/plain"), want: "data", wantContentType: "text/plain"},
+		{name: "Exactly Limit", response: bytes.NewReader(make([]byte, MaxLambdaInvokeSize)), want: string(make([]byte, MaxLambdaInvokeSize))},
+		{name: "Too Large", response: bytes.NewReader(make([]byte, MaxLambdaInvokeSize+10)), wantSize: MaxLambdaInvokeSize + 10},
+		{name: "Overflow", response: bytes.NewReader(make([]byte, MaxLambdaInvokeSize+10)), overflow: overflow, want: strconv.Itoa(MaxLambdaInvokeSize + 10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := newRuntime(nil, nil, nil)
+			rt.overflow = tt.overflow
+
+			got, err := rt.limitResponse(context.Background(), tt.response)
+			if tt.wantSize != 0 {
+				var sizeErr ResponseSizeError
+				if !errors.As(err, &sizeErr) || sizeErr.Size != tt.wantSize || sizeErr.Limit != MaxLambdaInvokeSize {
+					t.Fatalf("runtime.limitResponse() error = %v, want ResponseSizeError of %d bytes", err, tt.wantSize)
+				}
+				if sizeErr.Type() != responseSizeErrorHeader {
+					t.Errorf("ResponseSizeError.Type() = %s, want %s", sizeErr.Type(), responseSizeErrorHeader)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runtime.limitResponse() error = %v", err)
+			}
+			if got == nil {
+				if tt.response != nil {
+					t.Fatal("runtime.limitResponse() returned a nil response")
+				}
+				return
+			}
+
+			data, _ := io.ReadAll(got)
+			if string(data) != tt.want {
+				t.Errorf("runtime.limitResponse() returned %d bytes, want %d", len(data), len(tt.want))
+			}
+			if resp, ok := got.(Response); tt.wantContentType != "" && (!ok || resp.ContentType() != tt.wantContentType) {
+				t.Errorf("runtime.limitResponse() did not preserve content type %s", tt.wantContentType)
+			}
+		})
+	}
+}
+
+func Test_runtime_next_responseTooLarge(t *testing.T) {
+	var reported error
+	rt := newRuntime(
+		func(ctx context.Context, r io.Reader) (io.Reader, error) {
+			return bytes.NewReader(make([]byte, MaxLambdaInvokeSize+1)), nil
+		},
+		mockAPI{
+			_getRuntimeInvocationNext: func() (*http.Response, error) {
+				return newValidNextResponse(), nil
+			},
+			_postRuntimeInvocationError: func(requestId string, err error) (*http.Response, error) {
+				reported = err
+				return nil, nil
+			},
+		},
+		nil,
+	)
+
+	if err := rt.next(); err == nil {
+		t.Fatal("runtime.next() did not return the response size error")
+	}
+
+	var sizeErr ResponseSizeError
+	if !errors.As(reported, &sizeErr) || sizeErr.Size != MaxLambdaInvokeSize+1 {
+		t.Errorf("reported error = %v, want ResponseSizeError", reported)
+	}
+}
+
+func Test_runtime_shutdown(t *testing.T) {
+	calls := []string{}
+	rt := newRuntime(nil, nil, nil)
+	rt.shutdownTimeout = time.Minute
+	rt.shutdownHooks = []func(ctx context.Context){
+		func(ctx context.Context) {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("shutdown hook context has no deadline")
+			}
+			calls = append(calls, "first")
+		},
+		func(ctx context.Context) { panic("hook") },
+		func(ctx context.Context) { calls = append(calls, "third") },
+	}
+
+	rt.shutdown()
+	rt.shutdown()
+
+	if !reflect.DeepEqual(calls, []string{"first", "third"}) {
+		t.Errorf("shutdown hook calls = %v, want each surviving hook once", calls)
+	}
+	if !rt.stopping.Load() {
+		t.Error("runtime.shutdown() did not stop the loop")
+	}
+}
+
+func Test_runtime_start_stopping(t *testing.T) {
+	polled := 0
+	rt := newRuntime(
+		func(ctx context.Context, r io.Reader) (io.Reader, error) { return nil, nil },
+		mockAPI{
+			_getRuntimeInvocationNext: func() (*http.Response, error) {
+				polled++
+				return newValidNextResponse(), nil
+			},
+			_postRuntimeInvocationResponse: func(requestId string, response io.Reader) (*http.Response, error) {
+				return nil, nil
+			},
+		},
+		nil,
+	)
+	rt.shutdownHooks = []func(ctx context.Context){
+		func(ctx context.Context) {},
+	}
+	rt.hooks = []Hooks{{AfterInvoke: func(ctx context.Context, err error) { rt.stopping.Store(true) }}}
+
+	rt.start()
+
+	if polled != 1 {
+		t.Errorf("runtime.start() polled %d times after shutdown began, want 1", polled)
+	}
+}
+
+func Test_runtime_start_invocationLimit(t *testing.T) {
+	polled, hooked := 0, false
+	rt := newRuntime(
+		func(ctx context.Context, r io.Reader) (io.Reader, error) { return nil, nil },
+		mockAPI{
+			_getRuntimeInvocationNext: func() (*http.Response, error) {
+				polled++
+				return newValidNextResponse(), nil
+			},
+			_postRuntimeInvocationResponse: func(requestId string, response io.Reader) (*http.Response, error) {
+				return nil, nil
+			},
+		},
+		nil,
+	)
+	rt.maxInvocations = 3
+	rt.shutdownHooks = []func(ctx context.Context){func(ctx context.Context) { hooked = true }}
+
+	rt.start()
+
+	if polled != 3 || !hooked {
+		t.Errorf("runtime.start() polled %d times, shutdown hooks ran = %v, want 3 polls and hooks", polled, hooked)
+	}
+}
+
+func Test_runtime_watchSignals(t *testing.T) {
+	exited := make(chan int, 1)
+	hooked := false
+
+	rt := newRuntime(nil, nil, nil)
+	rt.shutdownHooks = []func(ctx context.Context){func(ctx context.Context) { hooked = true }}
+	rt.exit = func(code int) { exited <- code }
+
+	stop := rt.watchSignals()
+	defer stop()
+
+	syscall.Kill(os.Getpid(), syscall.SIGTERM)
+
+	select {
+	case code := <-exited:
+		if code != 0 || !hooked {
+			t.Errorf("SIGTERM exited with %d, hooks ran = %v", code, hooked)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGTERM did not shut the runtime down")
+	}
+}
+
+func Test_runtime_start_init(t *testing.T) {
+	initErr := NewError(errors.New("error"), "Custom.InitError", "Custom.InitError")
+
+	tests := []struct {
+		name        string
+		init        InitFunc
+		wantInitErr string
+	}{
+		{
+			name: "Error",
+			init: func(ctx context.Context) (Handler, error) {
+				return nil, initErr
+			},
+			wantInitErr: "Custom.InitError",
+		},
+		{
+			name: "Nil Handler",
+			init: func(ctx context.Context) (Handler, error) {
+				return nil, nil
+			},
+			wantInitErr: defaultInitErrorHeader,
+		},
+		{
+			name: "Panic",
+			init: func(ctx context.Context) (Handler, error) {
+				panic("boom")
+			},
+			wantInitErr: defaultInitErrorHeader,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reported Error
+			posts := 0
+			rt := newRuntime(nil, mockAPI{
+				_postRuntimeInitError: func(err error) (*http.Response, error) {
+					reported = asError(err, defaultInitErrorHeader)
+					posts++
+					return nil, nil
+				},
+			}, defaultFatal)
+			rt.init = tt.init
+
+			rt.start()
+
+			if reported == nil || reported.Type() != tt.wantInitErr {
+				t.Errorf("runtime.start() reported init error %v, want type %s", reported, tt.wantInitErr)
+			}
+			if posts != 1 {
+				t.Errorf("runtime.start() posted the init error %d times, want 1", posts)
+			}
+		})
+	}
+}
+
+func Test_runtime_next_coldStart(t *testing.T) {
+	metas := []RequestMeta{}
+	rt := newRuntime(
+		func(ctx context.Context, r io.Reader) (io.Reader, error) {
+			metas = append(metas, MustRequestMeta(ctx))
+			return nil, nil
+		},
+		mockAPI{
+			_getRuntimeInvocationNext: func() (*http.Response, error) {
+				return newValidNextResponse(), nil
+			},
+			_postRuntimeInvocationResponse: func(requestId string, response io.Reader) (*http.Response, error) {
+				return nil, nil
+			},
+		},
+		nil,
+	)
+	rt.init = func(ctx context.Context) (Handler, error) {
+		time.Sleep(time.Millisecond)
+		return rt.handler, nil
+	}
+
+	if err := rt.initialize(); err != nil {
+		t.Fatalf("runtime.initialize() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := rt.next(); err != nil {
+			t.Fatalf("runtime.next() error = %v", err)
+		}
+		rt.reset()
+	}
+
+	if !metas[0].ColdStart || metas[0].InitDuration < time.Millisecond {
+		t.Errorf("first invocation RequestMeta = %+v, want cold start with init duration", metas[0])
+	}
+	if metas[1].ColdStart || metas[1].InitDuration != 0 {
+		t.Errorf("second invocation RequestMeta = %+v, want warm start", metas[1])
+	}
+}
+
+func Test_runtime_updateMeta_trace(t *testing.T) {
+	header := "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"
+
+	tests := []struct {
+		name    string
+		disable bool
+		want    string
+	}{
+		{name: "Sets Env", want: header},
+		{name: "Without Env", disable: true, want: "unchanged"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envTraceId, "unchanged")
+
+			resp := newValidNextResponse()
+			resp.Header.Set(headerTraceId, header)
+
+			rt := newRuntime(nil, nil, nil)
+			rt.disableTraceEnv = tt.disable
+			if err := rt.updateMeta(resp); err != nil {
+				t.Fatalf("runtime.updateMeta() error = %v", err)
+			}
+
+			if rt.meta.Trace.Root != "1-5759e988-bd862e3fe1be46a994272793" || !rt.meta.Trace.IsSampled() {
+				t.Errorf("runtime.updateMeta() Trace = %v", rt.meta.Trace)
+			}
+			if got := os.Getenv(envTraceId); got != tt.want {
+				t.Errorf("%s = %s, want %s", envTraceId, got, tt.want)
+			}
+		})
+	}
+}
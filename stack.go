@@ -0,0 +1,64 @@
+package llb
+
+import (
+	"fmt"
+	goruntime "runtime"
+	"strings"
+)
+
+type (
+	stackError struct {
+		err   Error
+		stack []uintptr
+	}
+	// StackTracer is implemented by errors that captured a stack trace, it is reported in the stackTrace field of error payloads
+	StackTracer interface {
+		StackTrace() []string
+	}
+)
+
+const (
+	maxStackDepth = 64
+)
+
+var (
+	_ = Error(stackError{})
+	_ = StackTracer(stackError{})
+)
+
+// NewErrorWithStack creates an Error like NewError that also captures the stack trace of its caller
+func NewErrorWithStack(err error, header, typ string) Error {
+	return withStack(NewError(err, header, typ), 3)
+}
+
+// withStack captures the stack above skip frames, runtime frames are left out so a stack captured while recovering starts at the panic site
+func withStack(err Error, skip int) Error {
+	pcs := make([]uintptr, maxStackDepth)
+	n := goruntime.Callers(skip, pcs)
+
+	return stackError{
+		err:   err,
+		stack: pcs[:n],
+	}
+}
+
+func (se stackError) Error() string  { return se.err.Error() }
+func (se stackError) Header() string { return se.err.Header() }
+func (se stackError) Type() string   { return se.err.Type() }
+func (se stackError) Unwrap() error  { return se.err }
+
+func (se stackError) StackTrace() []string {
+	trace := []string{}
+	frames := goruntime.CallersFrames(se.stack)
+
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" && !strings.HasPrefix(frame.Function, "runtime.") {
+			trace = append(trace, fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line))
+		}
+
+		if !more {
+			return trace
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package llb
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewErrorWithStack(t *testing.T) {
+	err := NewErrorWithStack(errors.New("test"), "header", "typ")
+	if err.Error() != "test" || err.Header() != "header" || err.Type() != "typ" {
+		t.Fatal("NewErrorWithStack did not preserve the error, header and type passed in")
+	}
+
+	tracer, ok := err.(StackTracer)
+	if !ok {
+		t.Fatal("NewErrorWithStack did not return a StackTracer")
+	}
+
+	trace := tracer.StackTrace()
+	if len(trace) == 0 || !strings.Contains(trace[0], "TestNewErrorWithStack") {
+		t.Fatalf("StackTrace() does not start at the caller: %v", trace)
+	}
+	for _, frame := range trace {
+		if strings.HasPrefix(frame, "runtime.") {
+			t.Fatalf("StackTrace() contains runtime frame %s", frame)
+		}
+	}
+}
+
+func TestNewErrorWithStackUnwrap(t *testing.T) {
+	cause := errors.New("test")
+	if err := NewErrorWithStack(cause, "header", "typ"); !errors.Is(err, cause) {
+		t.Fatal("NewErrorWithStack did not unwrap to the error passed in")
+	}
+}
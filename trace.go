@@ -0,0 +1,88 @@
+package llb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type (
+	// TraceHeader is a parsed X-Ray trace header, see https://docs.aws.amazon.com/xray/latest/devguide/xray-concepts.html#xray-concepts-tracingheader
+	TraceHeader struct {
+		Root    string
+		Parent  string
+		Sampled string
+		Lineage string
+	}
+)
+
+const (
+	// HeaderXRayTraceId carries the trace header on outgoing HTTP requests
+	HeaderXRayTraceId = "X-Amzn-Trace-Id"
+
+	traceRootKey    = "Root"
+	traceParentKey  = "Parent"
+	traceSampledKey = "Sampled"
+	traceLineageKey = "Lineage"
+)
+
+// ParseTraceHeader parses an X-Ray trace header such as "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1", unknown keys are ignored
+func ParseTraceHeader(header string) (TraceHeader, error) {
+	trace := TraceHeader{}
+
+	for _, part := range strings.Split(header, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case traceRootKey:
+			trace.Root = value
+		case traceParentKey:
+			trace.Parent = value
+		case traceSampledKey:
+			trace.Sampled = value
+		case traceLineageKey:
+			trace.Lineage = value
+		}
+	}
+
+	if trace.Root == "" {
+		return TraceHeader{}, errors.New("trace header has no Root; ParseTraceHeader")
+	}
+
+	return trace, nil
+}
+
+// IsSampled reports whether the upstream sampling decision was to sample, it is false when the decision was deferred
+func (th TraceHeader) IsSampled() bool { return th.Sampled == "1" }
+
+// String formats the header in the X-Ray format, empty fields are left out
+func (th TraceHeader) String() string {
+	parts := make([]string, 0, 4)
+
+	for _, field := range []struct{ key, value string }{
+		{traceRootKey, th.Root},
+		{traceParentKey, th.Parent},
+		{traceSampledKey, th.Sampled},
+		{traceLineageKey, th.Lineage},
+	} {
+		if field.value != "" {
+			parts = append(parts, field.key+"="+field.value)
+		}
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// InjectTraceHeader sets the X-Amzn-Trace-Id header of req to the trace header of the invocation in ctx, req is left unchanged when ctx has no trace
+func InjectTraceHeader(ctx context.Context, req *http.Request) {
+	meta, ok := GetRequestMeta(ctx)
+	if !ok || meta.TraceId == "" {
+		return
+	}
+
+	req.Header.Set(HeaderXRayTraceId, meta.TraceId)
+}
@@ -0,0 +1,66 @@
+package llb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestParseTraceHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    TraceHeader
+		wantErr bool
+	}{
+		{
+			name:   "Full",
+			header: "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1;Lineage=a87bd80c:0",
+			want:   TraceHeader{Root: "1-5759e988-bd862e3fe1be46a994272793", Parent: "53995c3f42cd8ad8", Sampled: "1", Lineage: "a87bd80c:0"},
+		},
+		{
+			name:   "Root Only With Unknown Key",
+			header: "Root=1-5759e988-bd862e3fe1be46a994272793; Self=1-abc",
+			want:   TraceHeader{Root: "1-5759e988-bd862e3fe1be46a994272793"},
+		},
+		{name: "No Root", header: "Parent=53995c3f42cd8ad8", wantErr: true},
+		{name: "Not X-Ray", header: "trace", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTraceHeader(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTraceHeader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTraceHeader() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTraceHeaderString(t *testing.T) {
+	header := "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=0"
+	trace, _ := ParseTraceHeader(header)
+
+	if trace.String() != header {
+		t.Errorf("TraceHeader.String() = %s, want %s", trace.String(), header)
+	}
+	if trace.IsSampled() {
+		t.Error("TraceHeader.IsSampled() = true for Sampled=0")
+	}
+}
+
+func TestInjectTraceHeader(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	InjectTraceHeader(context.Background(), req)
+	if req.Header.Get(HeaderXRayTraceId) != "" {
+		t.Fatal("InjectTraceHeader set a header without RequestMeta")
+	}
+
+	ctx := context.WithValue(context.Background(), contextKey, RequestMeta{TraceId: "Root=1-abc;Sampled=1"})
+	InjectTraceHeader(ctx, req)
+	if got := req.Header.Get(HeaderXRayTraceId); got != "Root=1-abc;Sampled=1" {
+		t.Errorf("InjectTraceHeader set %q", got)
+	}
+}